@@ -0,0 +1,225 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// tryDeliver calls f like `Try` does, and passes the resulting Outcome to deliver,
+// even if f calls runtime.Goexit, right before the calling goroutine terminates.
+func tryDeliver(f interface{}, opts []Option, deliver func(*Outcome)) {
+	deliver(Try(f, append(opts[:len(opts):len(opts)], withGoexit(deliver))...))
+}
+
+// TryGo calls f in a new goroutine, recovering from any panic it may cause like
+// `Try` does, so that it cannot crash the program. The resulting Outcome, whose
+// stack trace reflects the goroutine running f, is delivered on the returned
+// channel, which is then closed.
+func TryGo(f func(), opts ...Option) <-chan *Outcome {
+	ch := make(chan *Outcome, 1)
+	go func() {
+		tryDeliver(f, opts, func(o *Outcome) {
+			ch <- o
+			close(ch)
+		})
+	}()
+	return ch
+}
+
+// Collect waits for an Outcome to be delivered on each of the provided channels,
+// such as those returned by TryGo, and returns the Outcomes in the same order as
+// the channels. Nil channels, as well as channels closed without delivering an
+// Outcome, get a nil entry, rather than blocking forever.
+func Collect(chs ...<-chan *Outcome) []*Outcome {
+	outcomes := make([]*Outcome, len(chs))
+	for i, ch := range chs {
+		if ch != nil {
+			outcomes[i] = <-ch
+		}
+	}
+	return outcomes
+}
+
+// TryAll calls all the functions it receives concurrently, each of them `Try`ed
+// in its own goroutine, and returns their Outcomes in the same order as the
+// functions, once all of them have completed.
+func TryAll(fns ...func()) []*Outcome {
+	outcomes := make([]*Outcome, len(fns))
+	var wg sync.WaitGroup
+	wg.Add(len(fns))
+	for i, f := range fns {
+		go func(i int, f func()) {
+			defer wg.Done()
+			tryDeliver(f, nil, func(o *Outcome) {
+				outcomes[i] = o
+			})
+		}(i, f)
+	}
+	wg.Wait()
+	return outcomes
+}
+
+// TryAllContext works like TryAll, except that each function receives a context
+// derived from ctx, which is cancelled as soon as any function fails, either by
+// panicking or by returning a non-nil error, signalling the remaining ones to
+// give up. Functions that have not started yet by then are not called at all,
+// and get an Outcome with the ERR_TRY_CONTEXT code instead, same as with
+// TryContext.
+func TryAllContext(ctx context.Context, fns ...func(context.Context) error) []*Outcome {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	outcomes := make([]*Outcome, len(fns))
+	var wg sync.WaitGroup
+	wg.Add(len(fns))
+	for i, f := range fns {
+		go func(i int, f func(context.Context) error) {
+			defer wg.Done()
+			if err := ctx.Err(); err != nil {
+				outcomes[i] = contextOutcome(err)
+				return
+			}
+			tryDeliver(func() error {
+				return f(ctx)
+			}, nil, func(o *Outcome) {
+				if o.level != OK || o.err != nil {
+					cancel()
+				}
+				outcomes[i] = o
+			})
+		}(i, f)
+	}
+	wg.Wait()
+	return outcomes
+}
+
+// TryContext calls f like `Try` does, but in a separate goroutine, and stops
+// waiting for it when ctx is done. In that case it returns an Outcome at ERROR
+// level, with code ERR_TRY_CONTEXT and Err set to ctx.Err(), so that it can be
+// checked with errors.Is(o.Err(), context.DeadlineExceeded) and the like.
+// If ctx is already done, f is not called at all.
+//
+// Note that f keeps running after TryContext returns, until it completes on its
+// own; any panic it causes is still recovered, and its Outcome is discarded.
+func TryContext(ctx context.Context, f interface{}, opts ...Option) *Outcome {
+	if err := ctx.Err(); err != nil {
+		return contextOutcome(err)
+	}
+	ch := make(chan *Outcome, 1)
+	go func() {
+		tryDeliver(f, opts, func(o *Outcome) {
+			ch <- o
+		})
+	}()
+	select {
+	case o := <-ch:
+		return o
+	case <-ctx.Done():
+		return contextOutcome(ctx.Err())
+	}
+}
+
+// contextOutcome returns an Outcome reporting that the context was done before
+// the Try-ed function completed.
+func contextOutcome(err error) *Outcome {
+	o := (&Outcome{
+		err:   err,
+		level: ERROR,
+		code:  ERR_TRY_CONTEXT,
+		text:  "TryContext: " + err.Error(),
+	}).stamp(time.Now())
+	notify(o)
+	return o
+}
+
+// TryTimeout calls f like `Try` does, but in a separate goroutine, and stops
+// waiting for it after the given duration. In that case it returns an Outcome at
+// ERROR level, with code ERR_TRY_TIMEOUT.
+//
+// Note that f keeps running after TryTimeout returns, until it completes on its
+// own; any panic it causes is still recovered, but its Outcome is discarded:
+// the one returned only reflects the timeout.
+func TryTimeout(d time.Duration, f interface{}, opts ...Option) *Outcome {
+	ch := make(chan *Outcome, 1)
+	go func() {
+		tryDeliver(f, opts, func(o *Outcome) {
+			ch <- o
+		})
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case o := <-ch:
+		return o
+	case <-timer.C:
+		o := (&Outcome{
+			level: ERROR,
+			code:  ERR_TRY_TIMEOUT,
+			text:  fmt.Sprintf("TryTimeout: not completed within %s", d),
+		}).stamp(time.Now())
+		notify(o)
+		return o
+	}
+}
+
+// TryRace calls all the functions it receives concurrently, each of them `Try`ed
+// in its own goroutine, and returns the Outcome of the first one to complete,
+// whether successfully or not.
+//
+// The remaining functions are abandoned, but keep running until they complete:
+// there is no way to stop them short of using TryRaceContext. Any panic they
+// may cause is still recovered, and their Outcomes are discarded.
+func TryRace(fns ...func() (interface{}, error)) *Outcome {
+	if len(fns) == 0 {
+		return argOutcome("TryRace: no functions to call")
+	}
+	ch := make(chan *Outcome, len(fns))
+	for _, f := range fns {
+		go func(f func() (interface{}, error)) {
+			tryDeliver(f, nil, func(o *Outcome) {
+				ch <- o
+			})
+		}(f)
+	}
+	return <-ch
+}
+
+// TryRaceContext works like TryRace, except that each function receives a
+// context derived from ctx, which is cancelled as soon as the first function
+// completes, signalling the remaining ones to give up.
+// The functions are expected to honor the cancellation of the context they
+// receive; TryRaceContext waits for the first one to complete, even if ctx is
+// cancelled.
+func TryRaceContext(ctx context.Context, fns ...func(context.Context) (interface{}, error)) *Outcome {
+	if len(fns) == 0 {
+		return argOutcome("TryRaceContext: no functions to call")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch := make(chan *Outcome, len(fns))
+	for _, f := range fns {
+		go func(f func(context.Context) (interface{}, error)) {
+			tryDeliver(func() (interface{}, error) {
+				return f(ctx)
+			}, nil, func(o *Outcome) {
+				ch <- o
+			})
+		}(f)
+	}
+	return <-ch
+}
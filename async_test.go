@@ -0,0 +1,301 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTryGo(t *testing.T) {
+	done := false
+	out := <-TryGo(func() { done = true })
+	if ol := out.Level(); ol != OK || !done {
+		t.Errorf(`<-TryGo(goodFunc) = %q, want %q after calling the function`, LevelName(ol), LevelName(OK))
+	}
+
+	worker := func() {
+		panic("test")
+	}
+	ch := TryGo(worker)
+	out = <-ch
+	if ol := out.Level(); ol != PANIC {
+		t.Errorf(`<-TryGo(worker).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(PANIC))
+	}
+	if info := out.Info(); len(info) != 1 || !strings.Contains(info[0], "calmly.TestTryGo.func") || !strings.Contains(info[0], "calmly.TryGo.func") {
+		t.Errorf(`<-TryGo(worker).Info() does not contain the worker's stack trace (got %q)`, info)
+	}
+	if _, ok := <-ch; ok {
+		t.Errorf(`TryGo(worker) channel should be closed after delivering the Outcome`)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	if outs := Collect(); len(outs) != 0 {
+		t.Errorf(`Collect() = %v, want none`, outs)
+	}
+	closed := make(chan *Outcome)
+	close(closed)
+	outs := Collect(
+		TryGo(func() { time.Sleep(10 * time.Millisecond); panic("test") }),
+		nil,
+		TryGo(func() {}),
+		closed,
+	)
+	if len(outs) != 4 {
+		t.Fatalf(`len(Collect(...)) = %d, want %d`, len(outs), 4)
+	}
+	if outs[0] == nil || outs[0].Level() != PANIC {
+		t.Errorf(`Collect(...)[0] = %v, want a PANIC Outcome`, outs[0])
+	}
+	if outs[2] == nil || outs[2].Level() != OK {
+		t.Errorf(`Collect(...)[2] = %v, want an OK Outcome`, outs[2])
+	}
+	if outs[1] != nil || outs[3] != nil {
+		t.Errorf(`Collect(...) = %v, want nil entries for the nil and closed channels`, outs)
+	}
+}
+
+func TestTryAll(t *testing.T) {
+	if outs := TryAll(); len(outs) != 0 {
+		t.Errorf(`len(TryAll()) = %d, want %d`, len(outs), 0)
+	}
+
+	fns := make([]func(), 100)
+	for i := range fns {
+		n := i
+		fns[i] = func() {
+			if n%3 == 0 {
+				panic(n)
+			}
+		}
+	}
+	outs := TryAll(fns...)
+	if len(outs) != len(fns) {
+		t.Fatalf(`len(TryAll(fns...)) = %d, want %d`, len(outs), len(fns))
+	}
+	for i, out := range outs {
+		if i%3 == 0 {
+			if pv := out.PanicValue(); pv != i {
+				t.Errorf(`TryAll(fns...)[%d].PanicValue() = %v, want %v`, i, pv, i)
+			}
+		} else if ol := out.Level(); ol != OK {
+			t.Errorf(`TryAll(fns...)[%d].Level() = %q (%d), want %q`, i, LevelName(ol), ol, LevelName(OK))
+		}
+	}
+}
+
+func TestTryAllContext(t *testing.T) {
+	outs := TryAllContext(context.Background(),
+		func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+				return nil
+			}
+		},
+		func(ctx context.Context) error {
+			panic("test")
+		},
+	)
+	if len(outs) != 2 {
+		t.Fatalf(`len(TryAllContext(slow, panicFunc)) = %d, want %d`, len(outs), 2)
+	}
+	if oe := outs[0].Err(); !errors.Is(oe, context.Canceled) {
+		t.Errorf(`TryAllContext(slow, panicFunc)[0].Err() = %v, want %v`, oe, context.Canceled)
+	}
+	if ol := outs[1].Level(); ol != PANIC {
+		t.Errorf(`TryAllContext(slow, panicFunc)[1].Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(PANIC))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	outs = TryAllContext(ctx, func(context.Context) error { return nil })
+	if oc := outs[0].Code(); oc != ERR_TRY_CONTEXT {
+		t.Errorf(`TryAllContext(cancelled, f)[0].Code() = 0x%04x, want 0x%04x`, oc, ERR_TRY_CONTEXT)
+	}
+}
+
+func TestTryContext(t *testing.T) {
+	out := TryContext(context.Background(), func() interface{} {
+		return 17
+	})
+	if ol, ov := out.Level(), out.Value(); ol != OK || ov != 17 {
+		t.Errorf(`TryContext(bg, goodFunc) = (%q, %v), want (%q, %v)`, LevelName(ol), ov, LevelName(OK), 17)
+	}
+
+	out = TryContext(context.Background(), func() {
+		panic("test")
+	})
+	if ol, oc := out.Level(), out.Code(); ol != PANIC || oc != ERR_TRY_PANIC {
+		t.Errorf(`TryContext(bg, panicFunc) = (%q, 0x%04x), want (%q, 0x%04x)`, LevelName(ol), oc, LevelName(PANIC), ERR_TRY_PANIC)
+	}
+
+	defer awaitPanic(t, "too late")()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	out = TryContext(ctx, func() {
+		time.Sleep(50 * time.Millisecond)
+		panic("too late")
+	})
+	if ol, oc := out.Level(), out.Code(); ol != ERROR || oc != ERR_TRY_CONTEXT {
+		t.Errorf(`TryContext(timeout, slowFunc) = (%q, 0x%04x), want (%q, 0x%04x)`, LevelName(ol), oc, LevelName(ERROR), ERR_TRY_CONTEXT)
+	}
+	if !errors.Is(out.Err(), context.DeadlineExceeded) {
+		t.Errorf(`TryContext(timeout, slowFunc).Err() = %v, want %v`, out.Err(), context.DeadlineExceeded)
+	}
+
+	called := false
+	out = TryContext(ctx, func() {
+		called = true
+	})
+	if called || out.Code() != ERR_TRY_CONTEXT {
+		t.Errorf(`TryContext(done, f) should not call f`)
+	}
+}
+
+func TestTryTimeout(t *testing.T) {
+	out := TryTimeout(time.Second, func() interface{} {
+		return 17
+	})
+	if ol, ov := out.Level(), out.Value(); ol != OK || ov != 17 {
+		t.Errorf(`TryTimeout(1s, goodFunc) = (%q, %v), want (%q, %v)`, LevelName(ol), ov, LevelName(OK), 17)
+	}
+
+	defer awaitPanic(t, "too late")()
+	out = TryTimeout(10*time.Millisecond, func() {
+		time.Sleep(50 * time.Millisecond)
+		panic("too late")
+	})
+	if ol, oc := out.Level(), out.Code(); ol != ERROR || oc != ERR_TRY_TIMEOUT {
+		t.Errorf(`TryTimeout(10ms, slowFunc) = (%q, 0x%04x), want (%q, 0x%04x)`, LevelName(ol), oc, LevelName(ERROR), ERR_TRY_TIMEOUT)
+	}
+	if ot, exp := out.Text(), "TryTimeout: not completed within 10ms"; ot != exp {
+		t.Errorf(`TryTimeout(10ms, slowFunc).Text() = %q, want %q`, ot, exp)
+	}
+}
+
+func TestTryRace(t *testing.T) {
+	out := TryRace()
+	if ol, oc := out.Level(), out.Code(); ol != ERROR || oc != ERR_TRY_ARG {
+		t.Errorf(`TryRace() = (%q, 0x%04x), want (%q, 0x%04x)`, LevelName(ol), oc, LevelName(ERROR), ERR_TRY_ARG)
+	}
+
+	defer awaitPanic(t, "slow")()
+	out = TryRace(
+		func() (interface{}, error) {
+			time.Sleep(50 * time.Millisecond)
+			panic("slow")
+		},
+		func() (interface{}, error) {
+			return 17, nil
+		},
+	)
+	if ol := out.Level(); ol != OK {
+		t.Errorf(`TryRace(slowPanic, fast).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(OK))
+	}
+	if ov := out.Value(); ov != 17 {
+		t.Errorf(`TryRace(slowPanic, fast).Value() = %v, want %v`, ov, 17)
+	}
+
+	out = TryRace(
+		func() (interface{}, error) {
+			time.Sleep(50 * time.Millisecond)
+			return 17, nil
+		},
+		func() (interface{}, error) {
+			panic("fast")
+		},
+	)
+	if ol := out.Level(); ol != PANIC {
+		t.Errorf(`TryRace(slow, fastPanic).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(PANIC))
+	}
+}
+
+func TestTryRaceContext(t *testing.T) {
+	cancelled := make(chan bool, 1)
+	out := TryRaceContext(context.Background(),
+		func(ctx context.Context) (interface{}, error) {
+			select {
+			case <-ctx.Done():
+				cancelled <- true
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				cancelled <- false
+				return 0, nil
+			}
+		},
+		func(ctx context.Context) (interface{}, error) {
+			return 17, nil
+		},
+	)
+	if ov := out.Value(); ov != 17 {
+		t.Errorf(`TryRaceContext(slow, fast).Value() = %v, want %v`, ov, 17)
+	}
+	if !<-cancelled {
+		t.Errorf(`TryRaceContext(slow, fast) did not cancel the slow function`)
+	}
+}
+
+func TestTryGoexit(t *testing.T) {
+	exiting := func() {
+		runtime.Goexit()
+	}
+	out := <-TryGo(exiting)
+	if ol, oc := out.Level(), out.Code(); ol != ERROR || oc != ERR_TRY_GOEXIT {
+		t.Errorf(`<-TryGo(exiting) = (%q, 0x%04x), want (%q, 0x%04x)`, LevelName(ol), oc, LevelName(ERROR), ERR_TRY_GOEXIT)
+	}
+	if info := out.Info(); len(info) != 1 || !strings.Contains(info[0], "runtime.Goexit") || !strings.Contains(info[0], "calmly.TestTryGoexit.func") {
+		t.Errorf(`<-TryGo(exiting).Info() does not contain the stack trace of the Goexit call (got %q)`, info)
+	}
+
+	outs := TryAll(func() {}, exiting)
+	if oc := outs[1].Code(); outs[0].Level() != OK || oc != ERR_TRY_GOEXIT {
+		t.Errorf(`TryAll(goodFunc, exiting) = %v, want the second one with code 0x%04x`, outs, ERR_TRY_GOEXIT)
+	}
+	if oc := TryTimeout(time.Second, exiting).Code(); oc != ERR_TRY_GOEXIT {
+		t.Errorf(`TryTimeout(1s, exiting).Code() = 0x%04x, want 0x%04x`, oc, ERR_TRY_GOEXIT)
+	}
+
+	// only Goexit Outcomes are considered, as goroutines left running by other
+	// tests may pass their own to OnOutcome meanwhile
+	seen := make(chan *Outcome, 1)
+	defer setOnOutcome(func(o *Outcome) {
+		if o.Code() == ERR_TRY_GOEXIT {
+			select {
+			case seen <- o:
+			default:
+			}
+		}
+	})()
+	returned := false
+	go func() {
+		Try(exiting)
+		returned = true
+	}()
+	select {
+	case <-seen:
+		if returned {
+			t.Errorf(`Try(exiting) returned, want it to end the calling goroutine`)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf(`Try(exiting) did not pass an Outcome with code 0x%04x to OnOutcome`, ERR_TRY_GOEXIT)
+	}
+}
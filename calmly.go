@@ -15,7 +15,6 @@
 /*
 Package calmly implements convenient runtime panic recovery and handling
 
-
 When a panic condition needs to be handled by the program (rather than crashing it), wrap the code that can trigger such condition in a `Try`, which allows you to `Catch` the panic for further processing.
 
 The `Outcome` of a `Try`ed code also offers convenience methods to:
@@ -27,58 +26,452 @@ package calmly
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"reflect"
 	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Outcome represents the state of a `Try`ed call, including information about
 // any panic it may have triggered, as well as the returned value and error, if applicable.
+// An Outcome is not safe for concurrent use: if it is to be modified by several
+// goroutines, e.g. by Catch handlers, wrap it in a SyncOutcome.
 type Outcome struct {
-	val   interface{}
-	err   error
-	level int8
-	code  int
-	text  string
-	info  []string
+	val        interface{}
+	err        error
+	level      int8
+	code       int
+	text       string
+	info       []string
+	stacks     []int // indexes of the info entries holding captured stack traces
+	pcs        []uintptr
+	goroutines int
+	panicVal   interface{}
+	fields     map[string]interface{}
+	children   []*Outcome // the Outcomes combined by Merge
+	duration   time.Duration
+	handled    bool
+	stopped    bool
+	funcName   string
+	sampled    bool
+	time       time.Time
+	host       string
+}
+
+// FormatPanic converts the value recovered from a panic into the text of the
+// Outcome. It can be replaced to customize that text, e.g. to extract details
+// from application-specific error types; the recovered value is preserved
+// regardless, and is available via the PanicValue method of the Outcome.
+var FormatPanic = func(recovered interface{}) string {
+	return fmt.Sprintf("panic: %s", recovered)
+}
+
+// OnOutcome, if not nil, is called with every Outcome not at OK level produced
+// by Try and the other functions of this package that run code on the caller's
+// behalf, once the Outcome is fully populated, before it is returned. This allows
+// for centralized telemetry, such as counting panics.
+// OnOutcome is meant to be set during program initialization.
+var OnOutcome func(*Outcome)
+
+// notify calls the OnOutcome hook, if set, for non-OK Outcomes.
+func notify(o *Outcome) {
+	if h := OnOutcome; h != nil && o.level != OK {
+		h(o)
+	}
+}
+
+// argOutcome returns an Outcome reporting an invalid argument.
+func argOutcome(format string, a ...interface{}) *Outcome {
+	o := (&Outcome{
+		level: ERROR,
+		code:  ERR_TRY_ARG,
+		text:  fmt.Sprintf(format, a...),
+	}).stamp(time.Now())
+	notify(o)
+	return o
+}
+
+// Try calls the function it receives as argument, recovering from any panic it may cause.
+// The function must take no arguments, and may return nothing, a single value or
+// error, or a value and an error, in either order (see TryArgs). The common shapes,
+// such as func() error, are called directly, and any other ones via reflection.
+// The behavior of Try can be adjusted by passing one or more Options.
+//
+// If the Try-ed function calls runtime.Goexit, the goroutine running it cannot be
+// prevented from terminating, so Try does not return. The Outcome reporting it,
+// at ERROR level with code ERR_TRY_GOEXIT, is still passed to OnOutcome, and the
+// functions of this package running code in their own goroutines, such as TryGo,
+// deliver it as the result.
+func Try(f interface{}, opts ...Option) *Outcome {
+	return TryInto(&Outcome{}, f, opts...)
+}
+
+// okOutcome is the shared OK Outcome returned by TryFast.
+var okOutcome = &Outcome{}
+
+// TryFast works like Try, except that if f completes without panicking, and
+// returns no value and no error, the Outcome returned is a shared OK one, which
+// records neither the duration nor the timestamp, instead of a newly allocated
+// one. This avoids any allocation on the success path of code that rarely fails,
+// and only checks the level of the Outcome.
+//
+// The shared Outcome must not be modified: the methods that would modify it
+// panic, except for Reset and Release, which do nothing. Clone returns a copy
+// that can be modified.
+func TryFast(f interface{}, opts ...Option) *Outcome {
+	o := TryInto(Acquire(), f, opts...)
+	if o.level == OK && o.val == nil && o.err == nil && len(o.info) == 0 && len(o.fields) == 0 {
+		o.Release()
+		return okOutcome
+	}
+	return o
 }
 
-// Try calls the function it receives as argument, recovering from any panic it may cause
-func Try(f interface{}) (o *Outcome) {
+// writable panics if the receiver is the shared OK Outcome returned by TryFast.
+func (o *Outcome) writable() {
+	if o == okOutcome {
+		panic("calmly: cannot modify the shared OK Outcome returned by TryFast; use Clone")
+	}
+}
+
+// TryInto works like Try, except that it populates the provided Outcome, after
+// resetting it (see Reset), instead of allocating a new one, for code that needs
+// to avoid allocations, e.g. with Outcomes from Acquire. It returns o.
+func TryInto(o *Outcome, f interface{}, opts ...Option) (res *Outcome) {
+	o.writable()
+	// also return o if a panic is recovered
+	res = o
+	c := newConfig(opts)
+	o.Reset()
+	o.funcName = c.funcName
+	goexit := false
 	defer func() {
-		if err := recover(); err != nil {
-			o.level, o.code, o.text = PANIC, ERR_TRY_PANIC, fmt.Sprintf("panic: %s", err)
-			o.addInfo(2, "debug.stack")
+		if !c.deferHook || goexit {
+			notify(o)
+		}
+		if goexit && c.onGoexit != nil {
+			c.onGoexit(o)
+		}
+	}()
+	start, returned := time.Now(), false
+	o.stamp(start)
+	defer func() {
+		// a nil recovered value does not rule out a panic: with older Go versions
+		// (or GODEBUG=panicnil=1), panic(nil) is recovered as nil
+		if err := recover(); err != nil || !returned {
+			o.duration = time.Since(start)
+			if err == nil && goexiting() {
+				goexit = true
+				o.setGoexit(c)
+			} else {
+				o.setPanic(c, err)
+			}
 		}
 	}()
 
-	o = &Outcome{level: OK}
 	switch f := f.(type) {
 	case func():
 		f()
+		o.duration = time.Since(start)
 	case func() error:
 		o.err = f()
+		o.duration = time.Since(start)
 	case func() interface{}:
 		o.val = f()
+		o.duration = time.Since(start)
 	case func() (interface{}, error):
 		o.val, o.err = f()
+		o.duration = time.Since(start)
 	default:
-		o = &Outcome{
-			level: ERROR,
-			code:  ERR_TRY_ARG,
-			text:  fmt.Sprintf("Try: unsupported argument type %T", f),
+		if call := reflectFunc(f); call != nil {
+			o.val, o.err = call()
+			o.duration = time.Since(start)
+			break
+		}
+		o.level, o.code = ERROR, ERR_TRY_ARG
+		o.text = fmt.Sprintf("Try: unsupported argument type %T", f)
+		if c.hasCode {
+			o.code = c.code
 		}
 	}
+	returned = true
 	return
 }
 
+// TryCode works like Try, except that the Outcome gets the provided code instead
+// of the default one, if a panic or error condition occurs.
+func TryCode(code int, f interface{}, opts ...Option) *Outcome {
+	return Try(f, append(opts[:len(opts):len(opts)], WithCode(code))...)
+}
+
+// TryWith works like Try, except that if a panic is recovered, mapper is called
+// with the recovered value, to classify it according to the conventions of the
+// caller: the level, code and text of the Outcome it returns replace those set
+// by default, while its error (if not nil), info and fields are added. The stack
+// trace and the panic value are captured as usual, before mapper is called.
+// If mapper returns nil, the Outcome is the same as with Try.
+func TryWith(mapper func(recovered interface{}) *Outcome, f interface{}, opts ...Option) *Outcome {
+	return Try(f, append(opts[:len(opts):len(opts)], func(c *config) {
+		c.mapper = mapper
+	})...)
+}
+
+// TryResult calls f like Try does, and returns the value and error it returned,
+// for callers preferring a plain Go signature over handling the Outcome. If a
+// panic is recovered, the error returned is the Outcome itself, which includes
+// its code in the Error text and its stack trace when formatted with %+v.
+func TryResult(f func() (interface{}, error), opts ...Option) (interface{}, error) {
+	o := Try(f, opts...)
+	if o.level != OK {
+		return o.val, o
+	}
+	return o.val, o.err
+}
+
+// Then calls f like Try does, and returns the resulting Outcome, only if the
+// receiver is at OK level and holds no error returned by the Try-ed function;
+// otherwise, it returns the receiver unchanged. This allows for pipelines of
+// dependent steps: `calmly.Try(step1).Then(step2).Then(step3)`.
+func (o *Outcome) Then(f interface{}, opts ...Option) *Outcome {
+	if o.level != OK || o.err != nil {
+		return o
+	}
+	return Try(f, opts...)
+}
+
+// ThenWith works like Then, except that f receives the value stored by the
+// receiver, i.e. the one returned by the previous step.
+func (o *Outcome) ThenWith(f func(prev interface{}) (interface{}, error), opts ...Option) *Outcome {
+	if o.level != OK || o.err != nil {
+		return o
+	}
+	prev := o.val
+	return Try(func() (interface{}, error) {
+		return f(prev)
+	}, opts...)
+}
+
+// Recover is meant to be deferred at the top of a function, as in
+// `defer calmly.Recover(&out)`, to capture any panic occurring in that function
+// into an Outcome, like `Try` does. If *o is nil, or the shared OK Outcome returned
+// by TryFast, a new Outcome is allocated; otherwise, the existing one is updated.
+// If no panic occurs, *o is left unchanged.
+func Recover(o **Outcome, opts ...Option) {
+	if err := recover(); err != nil {
+		if *o == nil || *o == okOutcome {
+			*o = &Outcome{}
+		}
+		(*o).setPanic(newConfig(opts), err)
+		notify(*o)
+	}
+}
+
+// RecoverErr works like Recover, for functions with a named error result, as in
+// `defer calmly.RecoverErr(&err)`: if a panic occurs, *err is set to the Outcome
+// capturing it, whose message is the Outcome text (with its code), and which
+// unwraps to the panic value if that is an error. The Outcome itself, including
+// the stack trace, can be retrieved via errors.As.
+func RecoverErr(err *error, opts ...Option) {
+	if p := recover(); p != nil {
+		o := &Outcome{}
+		o.setPanic(newConfig(opts), p)
+		notify(o)
+		*err = o
+	}
+}
+
+// setPanic stores in the receiver the details of a recovered panic. It must be
+// called directly from the deferred function that recovered err.
+func (o *Outcome) setPanic(c config, err interface{}) {
+	o.level, o.panicVal = PANIC, err
+	if o.time.IsZero() {
+		o.stamp(time.Now())
+	}
+	capture := true
+	if inner, ok := err.(*Outcome); ok && inner != nil {
+		// an Outcome re-raised via Panic (or Must): propagate its details, and its
+		// stack trace if it comes from a recovered panic
+		o.code, o.text = inner.code, inner.text
+		if inner.panicVal != nil {
+			o.panicVal = inner.panicVal
+		}
+		if o.err == nil {
+			o.err = inner.err
+		}
+		o.appendInfo(inner)
+		for k, v := range inner.fields {
+			o.WithField(k, v)
+		}
+		if inner.pcs != nil {
+			o.pcs, o.goroutines, o.sampled = inner.pcs, inner.goroutines, inner.sampled
+			capture = false
+		}
+	} else {
+		o.code = ERR_TRY_PANIC
+		if _, ok := err.(*runtime.PanicNilError); ok || err == nil {
+			o.text = "panic: nil"
+		} else {
+			o.text = FormatPanic(err)
+		}
+		if e, ok := err.(error); ok && o.err == nil {
+			// the Try-ed function never got to return an error of its own
+			o.err = e
+		}
+	}
+	if capture {
+		o.pcs = callers(2)
+		if c.goroutines {
+			o.goroutines = runtime.NumGoroutine()
+		}
+		if CaptureStack && !c.noStack && sampleStack() {
+			o.addInfo(3, "debug.stack")
+			o.sampled = true
+		}
+	}
+	for _, f := range c.panicInfo {
+		o.info = append(o.info, panicInfo(f)...)
+	}
+	if c.hasCode {
+		o.code = c.code
+	}
+	if c.mapper != nil {
+		o.mapPanic(c.mapper, err)
+	}
+}
+
+// mapPanic applies to the receiver the Outcome produced by mapper for the value
+// recovered from a panic, if not nil, guarding against mapper panicking in turn.
+func (o *Outcome) mapPanic(mapper func(interface{}) *Outcome, recovered interface{}) {
+	defer func() {
+		if err := recover(); err != nil {
+			o.info = append(o.info, "TryWith: mapper "+FormatPanic(err))
+		}
+	}()
+	if m := mapper(recovered); m != nil {
+		o.level, o.code, o.text = m.level, m.code, m.text
+		if m.err != nil {
+			o.err = m.err
+		}
+		o.appendInfo(m)
+		for k, v := range m.fields {
+			o.WithField(k, v)
+		}
+	}
+}
+
+// setGoexit stores in the receiver the details of the Try-ed function calling
+// runtime.Goexit. It must be called directly from the deferred function that
+// detected it.
+func (o *Outcome) setGoexit(c config) {
+	o.level, o.code, o.text = ERROR, ERR_TRY_GOEXIT, "Try: runtime.Goexit called"
+	o.pcs = callers(2)
+	if c.goroutines {
+		o.goroutines = runtime.NumGoroutine()
+	}
+	if CaptureStack && !c.noStack && sampleStack() {
+		o.addInfo(3, "debug.stack")
+		o.sampled = true
+	}
+	if c.hasCode {
+		o.code = c.code
+	}
+}
+
+// panicInfo returns the lines produced by the OnPanicInfo callback f, guarding
+// against it panicking in turn.
+func panicInfo(f func() []string) (lines []string) {
+	defer func() {
+		if err := recover(); err != nil {
+			lines = []string{"OnPanicInfo: " + FormatPanic(err)}
+		}
+	}()
+	return f()
+}
+
 // Catch calls the provided function passing the receiver Outcome as argument,
 // only if the Outcome is at PANIC level.
+//
+// Like the other Catch methods, Catch does not call f once a previous handler
+// in the chain called Stop, so that only the first matching handler runs, as in
+// `out.CatchType(ErrQuota{}, handleQuota).Catch(handleOther)` with handleQuota
+// calling Stop.
 func (o *Outcome) Catch(f func(*Outcome)) *Outcome {
-	if o.level == PANIC {
+	if o.level == PANIC && !o.stopped {
 		f(o)
 	}
 	return o
 }
 
+// CatchType calls the provided function passing the receiver Outcome as argument,
+// only if the value recovered from a panic has the same dynamic type as target,
+// as in `out.CatchType((*MyError)(nil), handleMyError)`. If target is a nil
+// pointer to an interface type, as in `(*fmt.Stringer)(nil)`, f is called if the
+// recovered value implements that interface. Like the other Catch methods, it
+// does not call f for Outcomes at OK level, such as those marked as Handled.
+func (o *Outcome) CatchType(target interface{}, f func(*Outcome)) *Outcome {
+	if o.level == OK || o.panicVal == nil || target == nil || o.stopped {
+		return o
+	}
+	tt, pt := reflect.TypeOf(target), reflect.TypeOf(o.panicVal)
+	if pt == tt || tt.Kind() == reflect.Ptr && tt.Elem().Kind() == reflect.Interface && pt.Implements(tt.Elem()) {
+		f(o)
+	}
+	return o
+}
+
+// CatchLevel calls the provided function passing the receiver Outcome as argument,
+// only if the Outcome is at the specified level.
+func (o *Outcome) CatchLevel(level int8, f func(*Outcome)) *Outcome {
+	if o.level == level && !o.stopped {
+		f(o)
+	}
+	return o
+}
+
+// CatchAny calls the provided function passing the receiver Outcome as argument,
+// only if the Outcome is in an error condition (i.e. at ERROR level or above).
+func (o *Outcome) CatchAny(f func(*Outcome)) *Outcome {
+	if o.IsError() && !o.stopped {
+		f(o)
+	}
+	return o
+}
+
+// OnPanic calls the provided function passing the receiver Outcome as argument,
+// only if the Outcome is at PANIC level. It is equivalent to Catch.
+func (o *Outcome) OnPanic(f func(*Outcome)) *Outcome {
+	return o.Catch(f)
+}
+
+// OnError calls the provided function passing the receiver Outcome as argument,
+// only if the Outcome is in an error condition (ERROR, PANIC or FATAL level, or a
+// custom level above ERROR). It is equivalent to CatchAny.
+func (o *Outcome) OnError(f func(*Outcome)) *Outcome {
+	return o.CatchAny(f)
+}
+
+// Finally calls the provided function passing the receiver Outcome as argument,
+// regardless of its level, even if a handler called Stop. It is meant for
+// cleanup that must always happen.
+func (o *Outcome) Finally(f func(*Outcome)) *Outcome {
+	f(o)
+	return o
+}
+
+// Tap calls the provided function passing the receiver Outcome as argument,
+// regardless of its level, e.g. to record a metric. Unlike Finally, it is meant
+// for observing the Outcome at any point in a chain of calls, as many times as
+// needed; f sees the Outcome as modified by the preceding calls in the chain.
+func (o *Outcome) Tap(f func(*Outcome)) *Outcome {
+	f(o)
+	return o
+}
+
 // KeepCalm downgrades a PANIC to ERROR level, to avoid triggering a panic upon
 // logging the outcome.
 func (o *Outcome) KeepCalm() *Outcome {
@@ -97,31 +490,260 @@ func (o *Outcome) Escalate() *Outcome {
 	return o
 }
 
-// Log sends the error-condition Outcome to the provided log, using the appropriate
+// KeepCalmIf works like KeepCalm, but only if the provided predicate returns
+// true for the receiver.
+func (o *Outcome) KeepCalmIf(pred func(*Outcome) bool) *Outcome {
+	if o.level == PANIC && pred(o) {
+		o.level = ERROR
+	}
+	return o
+}
+
+// EscalateIf works like Escalate, but only if the provided predicate returns
+// true for the receiver, e.g. for panics carrying a specific code.
+func (o *Outcome) EscalateIf(pred func(*Outcome) bool) *Outcome {
+	if o.level == PANIC && pred(o) {
+		o.level = FATAL
+	}
+	return o
+}
+
+// Handled marks the receiver as dealt with, e.g. by a Catch handler that resolved
+// the panic, by downgrading it to OK level, so that it is no longer logged or
+// reported as an error condition. The code, text and info are preserved, and
+// IsHandled reports whether the Outcome was marked as handled.
+func (o *Outcome) Handled() *Outcome {
+	if o.level != OK {
+		o.level, o.handled = OK, true
+	}
+	return o
+}
+
+// IsHandled reports whether the receiver was marked as handled, via Handled.
+func (o *Outcome) IsHandled() bool {
+	return o.handled
+}
+
+// Stop marks the receiver as consumed by a handler, so that the following Catch
+// handlers in the chain (including OnPanic and OnError) are not called, as if the
+// Outcome was at OK level, for deterministic dispatch to the first matching one.
+// Unlike Handled, it does not change the level of the Outcome.
+func (o *Outcome) Stop() *Outcome {
+	o.writable()
+	o.stopped = true
+	return o
+}
+
+// IsStopped reports whether a handler called Stop on the receiver.
+func (o *Outcome) IsStopped() bool {
+	return o.stopped
+}
+
+// Panic re-raises the receiver by calling panic with the Outcome itself as
+// argument, so that an outer Try (or recover) gets all its context: the code,
+// text, info, fields, error and panic value are propagated to the outer Outcome,
+// along with the stack trace of the panic the receiver recovered, if any.
+func (o *Outcome) Panic() {
+	panic(o)
+}
+
+// Must panics with the provided Outcome as argument (see Panic) if it is not at
+// OK level, including WARN and INFO, or with the error returned by the Try-ed
+// function, if any. Otherwise, it returns the Outcome, for chaining. It is meant
+// for fail-fast code, such as initialization:
+// `calmly.Must(calmly.Try(loadConfig))`.
+func Must(o *Outcome) *Outcome {
+	if o.level != OK {
+		o.Panic()
+	}
+	if o.err != nil {
+		panic(o.err)
+	}
+	return o
+}
+
+// Log sends the non-OK Outcome to the provided log, using the appropriate
 // logging function: FATAL conditions are logged using Fatal(), PANIC using
-// Panic(), and ERROR using Print(). Non-error conditions are not logged
-// because there is no information stored in the Outcome, beside
-// what the Try-ed function returned (and is better suited to log itself).
+// Panic(), and all other levels (ERROR, WARN, INFO and any custom level) using
+// Print(). OK outcomes are not logged because there is no information stored
+// in the Outcome, beside what the Try-ed function returned (and is better
+// suited to log itself).
 func (o *Outcome) Log(log Logger) *Outcome {
 	switch o.level {
+	case OK:
 	case FATAL:
 		log.Fatal(o)
 	case PANIC:
 		log.Panic(o)
-	case ERROR:
+	default:
 		log.Print(o)
 	}
 	return o
 }
 
+// LogGraceful works like Log, except that it never delegates to the Fatal or Panic
+// methods of the logger, for servers that need to control their own shutdown:
+// FATAL and PANIC conditions are logged using Print(), like the other levels,
+// and the Outcome is then returned as the error, so that the caller can decide
+// whether to shut down gracefully, or re-raise the panic via the Panic method.
+// For all other levels, the returned error is nil.
+func (o *Outcome) LogGraceful(log Logger) (*Outcome, error) {
+	if o.level == OK {
+		return o, nil
+	}
+	log.Print(o)
+	if o.level == FATAL || o.level == PANIC {
+		return o, o
+	}
+	return o, nil
+}
+
+// Clone returns a copy of the receiver, which can be handled (e.g. downgraded,
+// escalated, or have info or fields added) independently of the original. The
+// info and fields are copied, while the value and error returned by the Try-ed
+// function, the recovered panic value and the children of a merged Outcome are
+// shared with the original.
+func (o *Outcome) Clone() *Outcome {
+	c := *o
+	if o.info != nil {
+		c.info = make([]string, len(o.info))
+		copy(c.info, o.info)
+	}
+	if o.stacks != nil {
+		c.stacks = append([]int(nil), o.stacks...)
+	}
+	if o.children != nil {
+		c.children = append([]*Outcome(nil), o.children...)
+	}
+	if o.fields != nil {
+		c.fields = make(map[string]interface{}, len(o.fields))
+		for k, v := range o.fields {
+			c.fields[k] = v
+		}
+	}
+	return &c
+}
+
+// Reset returns the receiver to the state of a new OK Outcome, so that it can be
+// reused (e.g. from a sync.Pool), while retaining the capacity allocated for its
+// info and fields. Any other references to the Outcome must no longer be in use.
+func (o *Outcome) Reset() *Outcome {
+	if o == okOutcome {
+		return o
+	}
+	info, stacks, fields := o.info[:0], o.stacks[:0], o.fields
+	clear(fields)
+	*o = Outcome{info: info, stacks: stacks, fields: fields}
+	return o
+}
+
+// outcomes pools the Outcomes handed out by Acquire.
+var outcomes = sync.Pool{
+	New: func() interface{} {
+		return &Outcome{}
+	},
+}
+
+// Acquire returns an OK Outcome from a pool, for extremely high-throughput code
+// that cannot afford allocating one for every call, e.g. via
+// `o := calmly.Acquire(); defer calmly.Recover(&o)`. Once the Outcome is no longer
+// needed, it should be returned to the pool via Release.
+func Acquire() *Outcome {
+	return outcomes.Get().(*Outcome)
+}
+
+// Release resets the receiver (see Reset) and returns it to the pool used by
+// Acquire. Once released, the Outcome must not be used anymore, including by any
+// code it was passed to, such as a Logger keeping references to it.
+func (o *Outcome) Release() {
+	if o == okOutcome {
+		return
+	}
+	outcomes.Put(o.Reset())
+}
+
+// Equal reports whether the Outcomes a and b have the same level, code, text and
+// info, and either both hold no error or hold errors with the same message. It is
+// mostly meant for tests; see also EqualIgnoringStack.
+func Equal(a, b *Outcome) bool {
+	return equal(a, b, false)
+}
+
+// EqualIgnoringStack works like Equal, except that the stack traces in the info
+// of the Outcomes are skipped, since their exact text depends on the environment.
+func EqualIgnoringStack(a, b *Outcome) bool {
+	return equal(a, b, true)
+}
+
+// equal implements Equal and EqualIgnoringStack.
+func equal(a, b *Outcome, ignoreStack bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.level != b.level || a.code != b.code || a.text != b.text || (a.err == nil) != (b.err == nil) ||
+		a.err != nil && a.err.Error() != b.err.Error() {
+		return false
+	}
+	ai, bi := a.info, b.info
+	if ignoreStack {
+		ai, bi = a.Messages(), b.Messages()
+	}
+	if len(ai) != len(bi) {
+		return false
+	}
+	for i := range ai {
+		if ai[i] != bi[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// LogAbove works like Log, but only if the receiver is at the provided level or
+// above, e.g. to log only FATAL conditions on noisy paths.
+func (o *Outcome) LogAbove(level int8, log Logger) *Outcome {
+	if o.AtLeast(level) {
+		o.Log(log)
+	}
+	return o
+}
+
 // Level returns the error level stored by the receiver.
 func (o *Outcome) Level() int8 {
 	return o.level
 }
 
+// AtLeast reports whether the receiver is at the provided level or a more
+// severe one (see SeverityLess).
+func (o *Outcome) AtLeast(level int8) bool {
+	return !SeverityLess(o.level, level)
+}
+
+// IsOK reports whether the receiver is at OK level.
+func (o *Outcome) IsOK() bool {
+	return o.level == OK
+}
+
+// IsError reports whether the receiver is in an error condition, i.e. at ERROR,
+// PANIC or FATAL level, or a custom level above ERROR.
+func (o *Outcome) IsError() bool {
+	return o.level >= ERROR
+}
+
+// IsPanic reports whether the receiver is at PANIC level.
+func (o *Outcome) IsPanic() bool {
+	return o.level == PANIC
+}
+
+// IsFatal reports whether the receiver is at FATAL level.
+func (o *Outcome) IsFatal() bool {
+	return o.level == FATAL
+}
+
 // SetLevel sets the error level stored by the receiver.
 func (o *Outcome) SetLevel(l int8) *Outcome {
-	if levelName(l) != "?" {
+	o.writable()
+	if LevelName(l) != "?" {
 		o.level = l
 	}
 	return o
@@ -132,8 +754,28 @@ func (o *Outcome) Code() int {
 	return o.code
 }
 
+// CodeName returns the name registered for the error code stored by the receiver,
+// or the code in hexadecimal form if no name was registered for it.
+// It returns an empty string for OK outcomes.
+func (o *Outcome) CodeName() string {
+	if o.level == OK {
+		return ""
+	}
+	if name, ok := codeName(o.code); ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", o.code)
+}
+
+// HasCode reports whether the receiver is not at OK level and stores the
+// provided error code. OK outcomes never match, since the zero code is ERR_TRY_ARG.
+func (o *Outcome) HasCode(code int) bool {
+	return o.level != OK && o.code == code
+}
+
 // SetCode sets the error code stored by the receiver.
 func (o *Outcome) SetCode(c int) *Outcome {
+	o.writable()
 	o.code = c
 	return o
 }
@@ -145,6 +787,7 @@ func (o *Outcome) Text() string {
 
 // SetText sets the error text stored by the receiver.
 func (o *Outcome) SetText(t string) *Outcome {
+	o.writable()
 	o.text = t
 	return o
 }
@@ -154,13 +797,147 @@ func (o *Outcome) Info() []string {
 	return o.info
 }
 
+// Messages returns the error info stored by the receiver, except for the stack
+// traces captured into it, e.g. to display the context of a failure concisely,
+// separately from the full trace.
+func (o *Outcome) Messages() []string {
+	if len(o.stacks) == 0 {
+		return o.info
+	}
+	lines := make([]string, 0, len(o.info)-len(o.stacks))
+	next := 0
+	for i, line := range o.info {
+		if next < len(o.stacks) && o.stacks[next] == i {
+			next++
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Hostname is the name of the host recorded in Outcomes, as reported by
+// os.Hostname at program start. It can be replaced where the actual hostname is
+// not helpful, e.g. in containers, by the name of the instance or node.
+// Hostname is meant to be set during program initialization.
+var Hostname = hostname()
+
+// hostname returns the name of the host reported by the kernel, if available.
+func hostname() string {
+	h, _ := os.Hostname()
+	return h
+}
+
+// stamp records in the receiver the provided creation time, and the host.
+func (o *Outcome) stamp(t time.Time) *Outcome {
+	o.time, o.host = t, Hostname
+	return o
+}
+
+// Timestamp returns the time the receiver was created, i.e. when the Try-ed
+// function was called, or the panic was recovered, e.g. by Recover. It returns
+// the zero time for Outcomes not created by this package.
+func (o *Outcome) Timestamp() time.Time {
+	return o.time
+}
+
+// Host returns the name of the host the receiver was created on, i.e. the value
+// of Hostname at that time.
+func (o *Outcome) Host() string {
+	return o.host
+}
+
+// CaptureStack controls whether a stack trace is added to the info of Outcomes
+// when a panic is recovered. Capturing it is relatively expensive, so disabling
+// it may help code that recovers from panics very frequently, at the cost of
+// making those panics much harder to debug. The program counters are captured
+// regardless, so the Frames and OriginFrame methods of Outcome remain available.
+// See also the WithoutStack option, to disable stack capture for a single call.
+var CaptureStack = true
+
+// StackSampleRate, if greater than 1, limits the capture of stack traces (see
+// CaptureStack) to 1 in StackSampleRate recoveries, while the others only store
+// the panic text, for code that recovers from panics constantly, but still wants
+// occasional stack traces for diagnosis. The Sampled method of Outcome reports
+// whether a stack trace was captured.
+var StackSampleRate = 1
+
+// stackSamples counts the recoveries eligible for stack capture.
+var stackSamples atomic.Uint64
+
+// sampleStack reports whether a stack trace is to be captured for the current
+// recovery, according to StackSampleRate.
+func sampleStack() bool {
+	rate := StackSampleRate
+	if rate <= 1 {
+		return true
+	}
+	return (stackSamples.Add(1)-1)%uint64(rate) == 0
+}
+
+// Sampled reports whether a stack trace was captured into the info of the
+// receiver when the panic it stores was recovered.
+func (o *Outcome) Sampled() bool {
+	return o.sampled
+}
+
+// StackBufferSize is the initial size of the buffers used for capturing stack
+// traces. The buffers are grown as needed to hold the complete trace, and reused
+// afterwards, so a larger value only saves reallocations for programs with very
+// deep stacks.
+var StackBufferSize = 4096
+
+// stackBuffers pools the buffers used for capturing stack traces.
+var stackBuffers = sync.Pool{
+	New: func() interface{} {
+		size := StackBufferSize
+		if size <= 0 {
+			size = 4096
+		}
+		buffer := make([]byte, size)
+		return &buffer
+	},
+}
+
+// AllGoroutines controls whether captured stack traces include all goroutines,
+// rather than only the current one. Dumping all goroutines can be very verbose
+// in busy programs, but may help diagnose panics involving several of them.
+var AllGoroutines = false
+
+// TrimPath, if not empty, is removed from the beginning of the file paths in
+// stack traces captured from then on, like the -trimpath build flag does for
+// the binary, e.g. to avoid leaking build paths into logs. See also Sanitize.
+var TrimPath = ""
+
+// trimPath removes prefix from the beginning of the file paths listed in the
+// formatted stack trace st.
+func trimPath(st, prefix string) string {
+	return strings.ReplaceAll(st, "\n\t"+prefix, "\n\t")
+}
+
+// stack writes into the provided buffer, growing it as needed, the formatted
+// stack trace of the current goroutine, followed by those of all the others
+// if AllGoroutines is set.
+func stack(buffer *[]byte) []byte {
+	for {
+		if n := runtime.Stack(*buffer, AllGoroutines); n < len(*buffer) {
+			return (*buffer)[:n]
+		}
+		*buffer = make([]byte, 2*len(*buffer))
+	}
+}
+
 // addInfo adds (more) error info to the receiver.
 func (o *Outcome) addInfo(calldepth int, s ...string) *Outcome {
+	o.writable()
 	for i, line := range s {
 		if line == "debug.stack" {
-			calldepth *= 2
-			buffer := make([]byte, 4096)
-			buffer = buffer[:runtime.Stack(buffer, true)]
+			// copy s, so as to not alter the slice passed by the caller, if any
+			s = append([]string(nil), s...)
+			// also trim the frame of stack itself
+			calldepth = (calldepth + 1) * 2
+			pooled := stackBuffers.Get().(*[]byte)
+			buffer := stack(pooled)
 			var p1, p2, l int
 			for j, c := range buffer {
 				if c == 10 {
@@ -178,6 +955,11 @@ func (o *Outcome) addInfo(calldepth int, s ...string) *Outcome {
 			} else {
 				s[i] = string(buffer)
 			}
+			if TrimPath != "" {
+				s[i] = trimPath(s[i], TrimPath)
+			}
+			stackBuffers.Put(pooled)
+			o.stacks = append(o.stacks, len(o.info)+i)
 			break
 		}
 	}
@@ -185,37 +967,214 @@ func (o *Outcome) addInfo(calldepth int, s ...string) *Outcome {
 	return o
 }
 
+// appendInfo adds the error info of another Outcome to the receiver, keeping
+// track of the stack traces among it.
+func (o *Outcome) appendInfo(from *Outcome) {
+	for _, i := range from.stacks {
+		o.stacks = append(o.stacks, len(o.info)+i)
+	}
+	o.info = append(o.info, from.info...)
+}
+
 // AddInfo adds (more) error info to the receiver.
 func (o *Outcome) AddInfo(s ...string) *Outcome {
 	return o.addInfo(2, s...)
 }
 
+// AddInfof formats its arguments like fmt.Sprintf does, and adds the result as
+// a single line of error info to the receiver, like AddInfo.
+func (o *Outcome) AddInfof(format string, a ...interface{}) *Outcome {
+	return o.addInfo(2, fmt.Sprintf(format, a...))
+}
+
+// Sanitize removes the provided prefix from the beginning of the file paths in
+// the stack traces stored in the error info of the receiver, like TrimPath does
+// at capture time; the other info is left unchanged. The frames reported by
+// Frames and OriginFrame are not affected.
+func (o *Outcome) Sanitize(trimPrefix string) *Outcome {
+	if trimPrefix == "" {
+		return o
+	}
+	for _, i := range o.stacks {
+		o.info[i] = trimPath(o.info[i], trimPrefix)
+	}
+	return o
+}
+
+// WithField adds a key/value pair to the structured context of the receiver,
+// such as a request ID, replacing any previous value for the same key.
+func (o *Outcome) WithField(key string, value interface{}) *Outcome {
+	o.writable()
+	if o.fields == nil {
+		o.fields = make(map[string]interface{})
+	}
+	o.fields[key] = value
+	return o
+}
+
+// Fields returns the structured context of the receiver, as set by WithField.
+func (o *Outcome) Fields() map[string]interface{} {
+	return o.fields
+}
+
+// AddInfoSkip works like AddInfo, except that the given number of additional
+// frames are trimmed from a captured stack trace. This allows helpers wrapping
+// AddInfo to present stack traces starting at their caller, by passing 1.
+func (o *Outcome) AddInfoSkip(skip int, s ...string) *Outcome {
+	return o.addInfo(2+skip, s...)
+}
+
+// Goroutines returns the number of goroutines that existed when the panic stored
+// by the receiver was recovered, if Try was called WithGoroutineCount; otherwise
+// it returns 0.
+func (o *Outcome) Goroutines() int {
+	return o.goroutines
+}
+
+// Duration returns the time the Try-ed function ran for, until it returned or
+// panicked. It is 0 for Outcomes not produced by running a function, e.g. via Wrap
+// or Recover.
+func (o *Outcome) Duration() time.Duration {
+	return o.duration
+}
+
+// Func returns the name of the Try-ed function, as reported by the runtime, if
+// known; it is recorded by TryArgs. For closures, it is the synthetic name given
+// by the compiler, such as "main.run.func1".
+func (o *Outcome) Func() string {
+	return o.funcName
+}
+
 // Value provides the value returned by the Try-ed function, if any.
 func (o *Outcome) Value() interface{} {
 	return o.val
 }
 
-// Err provides the error returned by the Try-ed function, if any.
+// Err provides the error returned by the Try-ed function, if any, or the error
+// value that caused the recovered panic.
 func (o *Outcome) Err() error {
 	return o.err
 }
 
-// Result provides the value and error returned by the Try-ed function, if any.
+// SetValue sets the value stored by the receiver, as if returned by the Try-ed
+// function, e.g. when building an Outcome by hand.
+func (o *Outcome) SetValue(v interface{}) *Outcome {
+	o.writable()
+	o.val = v
+	return o
+}
+
+// SetErr sets the error stored by the receiver, as if returned by the Try-ed
+// function, e.g. when adapting other error sources. Like the error returned by
+// the Try-ed function, it does not change the level of the Outcome.
+func (o *Outcome) SetErr(err error) *Outcome {
+	o.writable()
+	o.err = err
+	return o
+}
+
+// PanicValue provides the value recovered from the panic caused by the Try-ed
+// function, exactly as returned by recover(), or nil if no panic occurred. If the
+// panic re-raised an Outcome holding a recovered panic (see Panic), its value is
+// provided instead, so that CatchType matches the original panic.
+func (o *Outcome) PanicValue() interface{} {
+	return o.panicVal
+}
+
+// Result provides the value and error returned by the Try-ed function, if any;
+// as with Err, the error may also be the one that caused the recovered panic.
 func (o *Outcome) Result() (interface{}, error) {
 	return o.val, o.err
 }
 
-// Error returns a string representation of the Outcome if it is in an error condition,
-// or an empty string if no error or panic occurred. Note that the Try-ed function
-// returning a non-nil error does not constitute an error condition for the Outcome.
+// Error returns a string representation of the Outcome if it is not at OK level,
+// or an empty string if no error or panic occurred. The WARN and INFO levels do
+// not represent error conditions, but their text is still returned, for logging
+// purposes. Note that the Try-ed function returning a non-nil error does not
+// constitute an error condition for the Outcome.
 // That error value can be retrieved via Err or Result.
 // This is also useful for satisfying the `error` interface.
 func (o *Outcome) Error() string {
 	if o.level == OK {
 		return ""
 	}
+	if f := ErrorFormat; f != nil {
+		return f(o)
+	}
+	return formatError(o)
+}
+
+// ErrorFormat produces the text returned by Error (and String) for Outcomes not
+// at OK level. It can be replaced, e.g. to include the level name or some fields,
+// but must not call Error or String itself. By default, or if set to nil, the
+// text of the Outcome is used, followed by its code, if not 0; FormatWithCodeName
+// also includes the name registered for the code.
+// ErrorFormat is meant to be set during program initialization.
+var ErrorFormat = formatError
+
+// formatError is the default ErrorFormat.
+func formatError(o *Outcome) string {
 	if o.code != 0 {
 		return o.text + fmt.Sprintf(" (code: 0x%04x)", o.code)
 	}
 	return o.text
 }
+
+// FormatWithCodeName is an alternative ErrorFormat, using the name registered
+// for the code of the Outcome, if any, followed by the code in hexadecimal form,
+// as in "timed out (code: ERR_DB_TIMEOUT (0x0011))", to make logs easier to
+// search: `calmly.ErrorFormat = calmly.FormatWithCodeName`.
+func FormatWithCodeName(o *Outcome) string {
+	if o.code == 0 {
+		return o.text
+	}
+	if name, ok := codeName(o.code); ok {
+		return o.text + fmt.Sprintf(" (code: %s (0x%04x))", name, o.code)
+	}
+	return formatError(o)
+}
+
+// String returns "OK" for OK outcomes, and the same text as Error otherwise.
+func (o *Outcome) String() string {
+	if o.level == OK {
+		return LevelName(OK)
+	}
+	return o.Error()
+}
+
+// Format implements fmt.Formatter. The %v and %s verbs produce the same text as
+// String, and %q a quoted version of it, while %+v also includes the fields
+// (sorted by key), the error info, the goroutine count and the duration, if
+// known, each field, info entry (such as a captured stack trace), count and
+// duration on a separate line.
+func (o *Outcome) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		io.WriteString(s, o.String())
+		if s.Flag('+') {
+			keys := make([]string, 0, len(o.fields))
+			for k := range o.fields {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(s, "\n%s: %v", k, o.fields[k])
+			}
+			for _, line := range o.info {
+				io.WriteString(s, "\n"+line)
+			}
+			if o.goroutines != 0 {
+				fmt.Fprintf(s, "\ngoroutines: %d", o.goroutines)
+			}
+			if o.duration != 0 {
+				fmt.Fprintf(s, "\nduration: %s", o.duration)
+			}
+		}
+	case 's':
+		io.WriteString(s, o.String())
+	case 'q':
+		fmt.Fprintf(s, "%q", o.String())
+	default:
+		fmt.Fprintf(s, "%%!%c(*calmly.Outcome=%s)", verb, o.String())
+	}
+}
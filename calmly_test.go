@@ -15,11 +15,73 @@
 package calmly
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
+// onOutcomeHook holds the function set via setOnOutcome.
+var onOutcomeHook struct {
+	sync.Mutex
+	f func(*Outcome)
+}
+
+// TestMain sets OnOutcome once, before any test runs, to a function calling the
+// one set via setOnOutcome, so that goroutines left running by a test do not
+// race with another test setting it.
+func TestMain(m *testing.M) {
+	OnOutcome = func(o *Outcome) {
+		onOutcomeHook.Lock()
+		f := onOutcomeHook.f
+		onOutcomeHook.Unlock()
+		if f != nil {
+			f(o)
+		}
+	}
+	os.Exit(m.Run())
+}
+
+// setOnOutcome makes OnOutcome call f, until the returned function is called.
+func setOnOutcome(f func(*Outcome)) (restore func()) {
+	onOutcomeHook.Lock()
+	prev := onOutcomeHook.f
+	onOutcomeHook.f = f
+	onOutcomeHook.Unlock()
+	return func() {
+		onOutcomeHook.Lock()
+		onOutcomeHook.f = prev
+		onOutcomeHook.Unlock()
+	}
+}
+
+// awaitPanic returns a function waiting until an Outcome recovering a panic with
+// the provided value is passed to OnOutcome, e.g. from a function abandoned by
+// TryTimeout, so that the function does not outlive the test.
+func awaitPanic(t *testing.T, value interface{}) (wait func()) {
+	seen := make(chan struct{})
+	var once sync.Once
+	restore := setOnOutcome(func(o *Outcome) {
+		if o.PanicValue() == value {
+			once.Do(func() { close(seen) })
+		}
+	})
+	return func() {
+		defer restore()
+		select {
+		case <-seen:
+		case <-time.After(5 * time.Second):
+			t.Errorf(`the abandoned function panicking with %v did not complete`, value)
+		}
+	}
+}
+
 type mockLogger struct {
 	log string
 }
@@ -37,13 +99,15 @@ func (ml *mockLogger) Panic(s ...interface{}) {
 func TestLevelNames(t *testing.T) {
 	for level, name := range map[int8]string{
 		OK:    "OK",
+		INFO:  "INFO",
+		WARN:  "WARN",
 		ERROR: "ERROR",
 		PANIC: "PANIC",
 		FATAL: "FATAL",
 		17:    "?",
 	} {
-		if levelName(level) != name {
-			t.Errorf(`levelName(%d) = %q, want %q`, level, levelName(level), name)
+		if LevelName(level) != name {
+			t.Errorf(`LevelName(%d) = %q, want %q`, level, LevelName(level), name)
 		}
 	}
 }
@@ -51,16 +115,16 @@ func TestLevelNames(t *testing.T) {
 func TestSetters(t *testing.T) {
 	out := &Outcome{}
 	if ol := out.Level(); ol != OK {
-		t.Errorf(`default.Level() = %q (%d), want %q`, levelName(ol), ol, levelName(OK))
+		t.Errorf(`default.Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(OK))
 	}
 	if out.Error() != "" {
 		t.Errorf(`default.Error() = %q, want %q`, out.Error(), "")
 	}
 	if ol := out.SetLevel(FATAL).Level(); ol != FATAL {
-		t.Errorf(`SetLevel(FATAL).Level() = %q (%d), want %q`, levelName(ol), ol, levelName(FATAL))
+		t.Errorf(`SetLevel(FATAL).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(FATAL))
 	}
 	if ol := out.SetLevel(17).Level(); ol != FATAL {
-		t.Errorf(`SetLevel(17).Level() = %q (%d), want %q (unchanged previous value)`, levelName(ol), ol, levelName(FATAL))
+		t.Errorf(`SetLevel(17).Level() = %q (%d), want %q (unchanged previous value)`, LevelName(ol), ol, LevelName(FATAL))
 	}
 	if out.SetCode(17).Code() != 17 {
 		t.Errorf(`SetCode(17).Code() = 0x%04x, want 0x%04x`, out.Code(), 17)
@@ -70,7 +134,7 @@ func TestSetters(t *testing.T) {
 	}
 	info := out.AddInfo("line 1", "line 2", "debug.stack").Info()
 	if len(info) != 3 {
-		t.Errorf(`len(AddInfo("line 1", "line 2", "debug.stack").Info()) = %q, want %q`, len(info), 3)
+		t.Errorf(`len(AddInfo("line 1", "line 2", "debug.stack").Info()) = %d, want %d`, len(info), 3)
 	} else {
 		if info[0] != "line 1" {
 			t.Errorf(`AddInfo("line 1", "line 2", "debug.stack").Info()[0] = %q, want %q`, info[0], "line 1")
@@ -91,6 +155,13 @@ func TestLog(t *testing.T) {
 	if log.log != "abc\n[PANIC] abc\n[FATAL] abc (code: 0x0011)\n" {
 		t.Errorf(`logging test got %q, want %q`, log.log, "abc\n[PANIC] abc\n[FATAL] abc (code: 0x0011)\n")
 	}
+
+	log = &mockLogger{}
+	out = &Outcome{text: "abc"}
+	out.SetLevel(INFO).Log(log).SetLevel(WARN).SetCode(17).Log(log)
+	if log.log != "abc\nabc (code: 0x0011)\n" {
+		t.Errorf(`logging test got %q, want %q`, log.log, "abc\nabc (code: 0x0011)\n")
+	}
 }
 
 func TestStack(t *testing.T) {
@@ -111,18 +182,18 @@ func TestStack(t *testing.T) {
 			t.Errorf(action+`.Value() = %v, want %v`, ov, nil)
 		}
 		oe := out.Err()
-		if oe != nil {
-			t.Errorf(action+`.Err() = %v, want %v`, oe, nil)
+		if pe, _ := out.PanicValue().(error); oe != pe {
+			t.Errorf(action+`.Err() = %v, want %v`, oe, pe)
 		}
 		if orv, ore := out.Result(); orv != ov || ore != oe {
-			t.Errorf(action+`.Result() should equal (`+action+`.Value(), `+action+`.Err()); got (%v, %v != %v, $v)`, orv, ore, ov, oe)
+			t.Errorf(action+`.Result() should equal (`+action+`.Value(), `+action+`.Err()); got (%v, %v != %v, %v)`, orv, ore, ov, oe)
 		}
 		if oes, exp := out.Error(), ot+fmt.Sprintf(` (code: 0x%04x)`, oc); oes != exp {
 			t.Errorf(action+`.Error() = %q, want %q`, oes, exp)
 		}
 		info := out.info
 		if len(info) != 1 {
-			t.Errorf(`len(`+action+`.Info()) = %q, want %q`, len(info), 1)
+			t.Errorf(`len(`+action+`.Info()) = %d, want %d`, len(info), 1)
 		} else {
 			if !strings.Contains(info[0], "goroutine") || !strings.Contains(info[0], "calmly.TestStack") {
 				t.Errorf(action+`.Info()[0] does not contain stack trace (got %q)`, info[0])
@@ -137,7 +208,7 @@ func TestStack(t *testing.T) {
 
 	out := Try(divByZero)
 	if ol := out.Level(); ol != PANIC {
-		t.Errorf(`Try(divByZero).Level() = %q (%d), want %q`, levelName(ol), ol, levelName(PANIC))
+		t.Errorf(`Try(divByZero).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(PANIC))
 	}
 	assertTryPanic(out, `Try(divByZero)`, `divide by zero`)
 	caught := false
@@ -149,18 +220,18 @@ func TestStack(t *testing.T) {
 		t.Errorf(`Try(divByZero).Catch(f) should call f(*Outcome) on PANIC`)
 	}
 	if ol := out.Level(); ol != PANIC {
-		t.Errorf(`Try(divByZero).Level() = %q (%d), want %q`, levelName(ol), ol, levelName(PANIC))
+		t.Errorf(`Try(divByZero).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(PANIC))
 	}
 	assertTryPanic(out, `Try(divByZero).Catch()`, `divide by zero`)
 	out.KeepCalm()
 	if ol := out.Level(); ol != ERROR {
-		t.Errorf(`Try(divByZero).KeepCalm().Level() = %q (%d), want %q`, levelName(ol), ol, levelName(ERROR))
+		t.Errorf(`Try(divByZero).KeepCalm().Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(ERROR))
 	}
 	assertTryPanic(out, `Try(divByZero).KeepCalm()`, `divide by zero`)
 
 	out = Try(divByZero).Escalate()
 	if ol := out.Level(); ol != FATAL {
-		t.Errorf(`Try(divByZero).Escalate().Level() = %q (%d), want %q`, levelName(ol), ol, levelName(FATAL))
+		t.Errorf(`Try(divByZero).Escalate().Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(FATAL))
 	}
 	assertTryPanic(out, `Try(divByZero).Escalate()`, `divide by zero`)
 
@@ -169,7 +240,7 @@ func TestStack(t *testing.T) {
 		return fmt.Errorf("divByZero should panic")
 	})
 	if ol := out.Level(); ol != PANIC {
-		t.Errorf(`Try(divByZeroErr).Level() = %q (%d), want %q`, levelName(ol), ol, levelName(PANIC))
+		t.Errorf(`Try(divByZeroErr).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(PANIC))
 	}
 	assertTryPanic(out, `Try(divByZeroErr)`, `divide by zero`)
 
@@ -178,7 +249,7 @@ func TestStack(t *testing.T) {
 		return 17
 	})
 	if ol := out.Level(); ol != PANIC {
-		t.Errorf(`Try(divByZeroVal).Level() = %q (%d), want %q`, levelName(ol), ol, levelName(PANIC))
+		t.Errorf(`Try(divByZeroVal).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(PANIC))
 	}
 	assertTryPanic(out, `Try(divByZeroVal)`, `divide by zero`)
 
@@ -187,7 +258,7 @@ func TestStack(t *testing.T) {
 		return 17, fmt.Errorf("divByZero should panic")
 	})
 	if ol := out.Level(); ol != PANIC {
-		t.Errorf(`Try(divByZeroValErr).Level() = %q (%d), want %q`, levelName(ol), ol, levelName(PANIC))
+		t.Errorf(`Try(divByZeroValErr).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(PANIC))
 	}
 	assertTryPanic(out, `Try(divByZeroValErr)`, `divide by zero`)
 
@@ -195,7 +266,7 @@ func TestStack(t *testing.T) {
 		return 17, nil
 	})
 	if ol := out.Level(); ol != OK {
-		t.Errorf(`Try(goodFunc).Level() = %q (%d), want %q`, levelName(ol), ol, levelName(OK))
+		t.Errorf(`Try(goodFunc).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(OK))
 	}
 	oc := out.Code()
 	if oc != 0 {
@@ -221,14 +292,14 @@ func TestStack(t *testing.T) {
 	}
 	info := out.info
 	if len(info) != 0 {
-		t.Errorf(`len(Try(goodFunc).Info()) = %q, want %q`, len(info), 0)
+		t.Errorf(`len(Try(goodFunc).Info()) = %d, want %d`, len(info), 0)
 	}
 
-	out = Try(func() (int, error) {
-		return 17, nil
+	out = Try(func(a int) (int, error) {
+		return a, nil
 	})
 	if ol := out.Level(); ol != ERROR {
-		t.Errorf(`Try(badFunc).Level() = %q (%d), want %q`, levelName(ol), ol, levelName(ERROR))
+		t.Errorf(`Try(badFunc).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(ERROR))
 	}
 	oc = out.Code()
 	if oc != ERR_TRY_ARG {
@@ -254,6 +325,1383 @@ func TestStack(t *testing.T) {
 	}
 	info = out.info
 	if len(info) != 0 {
-		t.Errorf(`len(Try(badFunc).Info()) = %q, want %q`, len(info), 0)
+		t.Errorf(`len(Try(badFunc).Info()) = %d, want %d`, len(info), 0)
+	}
+}
+
+func TestGoroutineCount(t *testing.T) {
+	panicFunc := func() {
+		panic("test")
+	}
+	if og := Try(panicFunc).Goroutines(); og != 0 {
+		t.Errorf(`Try(panicFunc).Goroutines() = %d, want %d`, og, 0)
+	}
+	if og := Try(func() {}, WithGoroutineCount()).Goroutines(); og != 0 {
+		t.Errorf(`Try(goodFunc, WithGoroutineCount()).Goroutines() = %d, want %d`, og, 0)
+	}
+	out := Try(panicFunc, WithGoroutineCount(), WithoutStack())
+	if og := out.Goroutines(); og < 1 {
+		t.Errorf(`Try(panicFunc, WithGoroutineCount()).Goroutines() = %d, want at least %d`, og, 1)
+	}
+	if got, exp := fmt.Sprintf("%+v", out.SetText("test")), fmt.Sprintf("test (code: 0x0001)\ngoroutines: %d\nduration: ", out.Goroutines()); !strings.HasPrefix(got, exp) {
+		t.Errorf(`fmt.Sprintf("%%+v", Try(panicFunc, WithGoroutineCount())) = %q, want the goroutine count before the duration`, got)
+	}
+}
+
+type testPanicError struct {
+	reason string
+}
+
+func (e testPanicError) Error() string {
+	return e.reason
+}
+
+func TestPanicValue(t *testing.T) {
+	out := Try(func() interface{} {
+		return 17
+	})
+	if opv := out.PanicValue(); opv != nil {
+		t.Errorf(`Try(goodFunc).PanicValue() = %v, want %v`, opv, nil)
+	}
+	out = Try(func() interface{} {
+		panic(testPanicError{"test"})
+	})
+	if e, ok := out.PanicValue().(testPanicError); !ok || e.reason != "test" {
+		t.Errorf(`Try(panicFunc).PanicValue() = %#v, want %#v`, out.PanicValue(), testPanicError{"test"})
+	}
+	if ov := out.Value(); ov != nil {
+		t.Errorf(`Try(panicFunc).Value() = %v, want %v`, ov, nil)
+	}
+	if ot := out.Text(); ot != "panic: test" {
+		t.Errorf(`Try(panicFunc).Text() = %q, want %q`, ot, "panic: test")
+	}
+}
+
+func TestFinally(t *testing.T) {
+	for name, out := range map[string]*Outcome{
+		"goodFunc":  Try(func() {}),
+		"panicFunc": Try(func() { panic("test") }),
+		"badFunc":   Try(17),
+		"fatal":     Try(func() { panic("test") }).Escalate(),
+	} {
+		var got *Outcome
+		if ret := out.Catch(func(*Outcome) {}).Finally(func(o *Outcome) { got = o }); ret != out {
+			t.Errorf(`Try(%s).Finally(f) should return its receiver`, name)
+		}
+		if got != out {
+			t.Errorf(`Try(%s).Finally(f) should call f(*Outcome) with its receiver`, name)
+		}
+	}
+}
+
+func TestCatchLevel(t *testing.T) {
+	for _, level := range []int8{OK, INFO, WARN, ERROR, PANIC, FATAL} {
+		out := (&Outcome{}).SetLevel(level)
+		for _, catch := range []int8{OK, INFO, WARN, ERROR, PANIC, FATAL} {
+			caught := false
+			out.CatchLevel(catch, func(*Outcome) { caught = true })
+			if caught != (catch == level) {
+				t.Errorf(`%s.CatchLevel(%s, f) called f: %v, want %v`, LevelName(level), LevelName(catch), caught, catch == level)
+			}
+		}
+		caught := false
+		out.CatchAny(func(*Outcome) { caught = true })
+		if caught != (level >= ERROR) {
+			t.Errorf(`%s.CatchAny(f) called f: %v, want %v`, LevelName(level), caught, level >= ERROR)
+		}
+	}
+
+	caught := false
+	Try(17).CatchLevel(ERROR, func(o *Outcome) {
+		caught = o.Code() == ERR_TRY_ARG
+	})
+	if !caught {
+		t.Errorf(`Try(badFunc).CatchLevel(ERROR, f) should call f(*Outcome) with the ERR_TRY_ARG Outcome`)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	divByZero := func() (out *Outcome) {
+		defer Recover(&out)
+		a, b := 1, 0
+		a = a / b
+		_ = a
+		return
+	}
+	out := divByZero()
+	if out == nil {
+		t.Fatalf(`divByZero() with deferred Recover(&out) returned nil`)
+	}
+	if ol, oc := out.Level(), out.Code(); ol != PANIC || oc != ERR_TRY_PANIC {
+		t.Errorf(`Recover(&out) = (%q, 0x%04x), want (%q, 0x%04x)`, LevelName(ol), oc, LevelName(PANIC), ERR_TRY_PANIC)
+	}
+	if ot := out.Text(); !strings.Contains(ot, "divide by zero") {
+		t.Errorf(`Recover(&out).Text() does not contain %q (got %q)`, "divide by zero", ot)
+	}
+	if info := out.Info(); len(info) != 1 {
+		t.Errorf(`len(Recover(&out).Info()) = %d, want %d`, len(info), 1)
+	} else if strings.Contains(info[0], "calmly.Recover") || strings.Contains(info[0], "setPanic") || !strings.Contains(info[0], "calmly.TestRecover") {
+		t.Errorf(`Recover(&out).Info()[0] does not start at the recovering function (got %q)`, info[0])
+	}
+
+	out = &Outcome{}
+	func() {
+		defer Recover(&out)
+	}()
+	if ol := out.Level(); ol != OK {
+		t.Errorf(`Recover(&out) without panic: Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(OK))
+	}
+}
+
+func TestFormat(t *testing.T) {
+	out := &Outcome{level: PANIC, code: 17, text: "abc", info: []string{"line 1", "line 2"}}
+	for format, exp := range map[string]string{
+		"%v":  "abc (code: 0x0011)",
+		"%s":  "abc (code: 0x0011)",
+		"%q":  `"abc (code: 0x0011)"`,
+		"%+v": "abc (code: 0x0011)\nline 1\nline 2",
+		"%d":  "%!d(*calmly.Outcome=abc (code: 0x0011))",
+	} {
+		if got := fmt.Sprintf(format, out); got != exp {
+			t.Errorf(`fmt.Sprintf(%q, out) = %q, want %q`, format, got, exp)
+		}
+	}
+	if got := fmt.Sprint(Try(func() {})); got != "OK" {
+		t.Errorf(`fmt.Sprint(Try(goodFunc)) = %q, want %q`, got, "OK")
+	}
+	out = Try(func() { panic("test") })
+	if got := fmt.Sprintf("%+v", out); !strings.HasPrefix(got, "panic: test (code: 0x0001)\ngoroutine") {
+		t.Errorf(`fmt.Sprintf("%%+v", Try(panicFunc)) does not contain stack trace (got %q)`, got)
+	}
+}
+
+func TestDeepStack(t *testing.T) {
+	var recurse func(int)
+	recurse = func(n int) {
+		if n == 0 {
+			panic("deep")
+		}
+		recurse(n - 1)
+	}
+	out := Try(func() { recurse(200) })
+	info := out.Info()
+	if len(info) != 1 {
+		t.Fatalf(`len(Try(deepPanic).Info()) = %d, want %d`, len(info), 1)
+	}
+	if !strings.Contains(info[0], "calmly.TestDeepStack(") || !strings.Contains(info[0], "testing.tRunner") {
+		t.Errorf(`Try(deepPanic).Info()[0] does not contain the complete stack trace (got %d bytes)`, len(info[0]))
+	}
+	if strings.Contains(info[0], "calmly.stack(") || strings.Contains(info[0], "calmly.(*Outcome).addInfo") {
+		t.Errorf(`Try(deepPanic).Info()[0] contains calmly internal frames`)
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	RegisterLevel(10, "ALERT")
+	if ln := LevelName(10); ln != "ALERT" {
+		t.Errorf(`LevelName(10) = %q, want %q`, ln, "ALERT")
+	}
+	out := (&Outcome{text: "abc"}).SetLevel(10)
+	if ol := out.Level(); ol != 10 {
+		t.Errorf(`SetLevel(10).Level() = %q (%d), want %q`, LevelName(ol), ol, "ALERT")
+	}
+	log := &mockLogger{}
+	out.Log(log)
+	if log.log != "abc\n" {
+		t.Errorf(`logging test got %q, want %q`, log.log, "abc\n")
+	}
+	caught := false
+	out.CatchAny(func(*Outcome) { caught = true })
+	if !caught {
+		t.Errorf(`ALERT.CatchAny(f) should call f(*Outcome)`)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf(`RegisterLevel(ERROR, "FAILURE") should panic`)
+		}
+		if ln := LevelName(ERROR); ln != "ERROR" {
+			t.Errorf(`LevelName(ERROR) = %q, want %q`, ln, "ERROR")
+		}
+	}()
+	RegisterLevel(ERROR, "FAILURE")
+}
+
+func TestRegisterCode(t *testing.T) {
+	if ocn := Try(func() { panic("test") }).CodeName(); ocn != "ERR_TRY_PANIC" {
+		t.Errorf(`Try(panicFunc).CodeName() = %q, want %q`, ocn, "ERR_TRY_PANIC")
+	}
+	if ocn := Try(17).CodeName(); ocn != "ERR_TRY_ARG" {
+		t.Errorf(`Try(badFunc).CodeName() = %q, want %q`, ocn, "ERR_TRY_ARG")
+	}
+	if ocn := Try(func() {}).CodeName(); ocn != "" {
+		t.Errorf(`Try(goodFunc).CodeName() = %q, want %q`, ocn, "")
+	}
+	// use a code not registered by a previous run, e.g. with -count=2
+	code := 0x0111
+	for _, ok := codeName(code); ok; _, ok = codeName(code) {
+		code++
+	}
+	out := (&Outcome{level: ERROR}).SetCode(code)
+	if ocn, exp := out.CodeName(), fmt.Sprintf("0x%04x", code); ocn != exp {
+		t.Errorf(`SetCode(0x%04x).CodeName() = %q, want %q`, code, ocn, exp)
+	}
+	RegisterCode(code, "ERR_TEST")
+	RegisterCode(code, "ERR_TEST")
+	if ocn := out.CodeName(); ocn != "ERR_TEST" {
+		t.Errorf(`SetCode(0x%04x).CodeName() = %q, want %q`, code, ocn, "ERR_TEST")
+	}
+	if oe, exp := FormatWithCodeName(out.SetText("abc")), fmt.Sprintf("abc (code: ERR_TEST (0x%04x))", code); oe != exp {
+		t.Errorf(`FormatWithCodeName(SetCode(0x%04x)) = %q, want %q`, code, oe, exp)
+	}
+	if oe, exp := FormatWithCodeName(out.SetCode(17)), "abc (code: 0x0011)"; oe != exp {
+		t.Errorf(`FormatWithCodeName(SetCode(17)) = %q, want %q`, oe, exp)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf(`RegisterCode(ERR_TRY_PANIC, "ERR_OTHER") should panic`)
+		}
+	}()
+	RegisterCode(ERR_TRY_PANIC, "ERR_OTHER")
+}
+
+func TestClone(t *testing.T) {
+	val := &struct{ n int }{17}
+	out := &Outcome{val: val, err: fmt.Errorf("test"), level: PANIC, code: 17, text: "abc", info: make([]string, 1, 10)}
+	c := out.Clone()
+	if c == out {
+		t.Fatalf(`Clone() returned its receiver`)
+	}
+	if c.Value() != out.Value() || c.Err() != out.Err() {
+		t.Errorf(`Clone().Result() = (%v, %v), want (%v, %v)`, c.Value(), c.Err(), out.Value(), out.Err())
+	}
+	if c.Level() != PANIC || c.Code() != 17 || c.Text() != "abc" {
+		t.Errorf(`Clone() = (%q, 0x%04x, %q), want (%q, 0x%04x, %q)`, LevelName(c.Level()), c.Code(), c.Text(), LevelName(PANIC), 17, "abc")
+	}
+	c.KeepCalm().AddInfo("clone")
+	out.Escalate().AddInfo("original")
+	if ol, cl := out.Level(), c.Level(); ol != FATAL || cl != ERROR {
+		t.Errorf(`levels after KeepCalm/Escalate = (%q, %q), want (%q, %q)`, LevelName(ol), LevelName(cl), LevelName(FATAL), LevelName(ERROR))
+	}
+	if oi, ci := out.Info(), c.Info(); oi[1] != "original" || ci[1] != "clone" {
+		t.Errorf(`info after AddInfo = (%q, %q), want (%q, %q)`, oi[1], ci[1], "original", "clone")
+	}
+}
+
+func TestPanic(t *testing.T) {
+	inner := Try(func() { panic("test") }).SetCode(17).AddInfo("enriched")
+	out := Try(func() {
+		inner.Panic()
+	})
+	if ol := out.Level(); ol != PANIC {
+		t.Errorf(`Try(inner.Panic).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(PANIC))
+	}
+	if opv := out.PanicValue(); opv != "test" {
+		t.Errorf(`Try(inner.Panic).PanicValue() = %v, want %v`, opv, "test")
+	}
+	caught := false
+	Try(func() {
+		Try(func() { panic(io.EOF) }).Panic()
+	}).CatchType((*error)(nil), func(o *Outcome) {
+		caught = o.Err() == io.EOF
+	})
+	if !caught {
+		t.Errorf(`CatchType((*error)(nil), f) does not catch a re-raised error panic with its error`)
+	}
+}
+
+func TestOnPanicOnError(t *testing.T) {
+	for _, level := range []int8{OK, INFO, WARN, ERROR, PANIC, FATAL} {
+		var panics, errs int
+		(&Outcome{level: level}).OnPanic(func(*Outcome) { panics++ }).OnError(func(*Outcome) { errs++ })
+		if panics != 0 != (level == PANIC) {
+			t.Errorf(`%s.OnPanic(f) called f %d times`, LevelName(level), panics)
+		}
+		if errs != 0 != (level >= ERROR) {
+			t.Errorf(`%s.OnError(f) called f %d times`, LevelName(level), errs)
+		}
+	}
+}
+
+func TestMust(t *testing.T) {
+	for name, out := range map[string]*Outcome{
+		"goodFunc": Try(func() {}),
+		"handled":  Try(func() { panic("test") }).Handled(),
+	} {
+		if ret := Try(func() interface{} { return Must(out) }); ret.Level() != OK || ret.Value() != out {
+			t.Errorf(`Must(%s) should return its argument without panicking (got %v)`, name, ret)
+		}
+	}
+	for name, out := range map[string]*Outcome{
+		"panicFunc": Try(func() { panic("test") }),
+		"badFunc":   Try(17),
+		"warn":      &Outcome{level: WARN, text: "careful"},
+	} {
+		exp := out.PanicValue()
+		if exp == nil {
+			exp = out
+		}
+		if ret := Try(func() { Must(out) }); ret.Level() != PANIC || ret.PanicValue() != exp {
+			t.Errorf(`Must(%s) should panic with its argument (got %v)`, name, ret.PanicValue())
+		}
+	}
+	ret := Try(func() { Must(Try(func() error { return io.EOF })) })
+	if rl, rt, re := ret.Level(), ret.Text(), ret.Err(); rl != PANIC || rt != "panic: EOF" || re != io.EOF {
+		t.Errorf(`Must(errFunc) recovered as (%q, %q, %v), want (%q, %q, %v)`, LevelName(rl), rt, re, LevelName(PANIC), "panic: EOF", io.EOF)
+	}
+}
+
+func TestOnOutcome(t *testing.T) {
+	var seen []*Outcome
+	defer setOnOutcome(func(o *Outcome) {
+		if o.Text() == "" || o.Level() == OK {
+			t.Errorf(`OnOutcome called with incomplete Outcome %#v`, o)
+		}
+		seen = append(seen, o)
+	})()
+
+	Try(func() {})
+	Try(func() error { return fmt.Errorf("test") })
+	if len(seen) != 0 {
+		t.Errorf(`OnOutcome called %d times for OK outcomes`, len(seen))
+	}
+	outs := []*Outcome{
+		Try(func() { panic("test") }),
+		Try(17),
+		TryArgs(17),
+		TryRace(),
+		func() (out *Outcome) {
+			defer Recover(&out)
+			panic("test")
+		}(),
+	}
+	if len(seen) != len(outs) {
+		t.Fatalf(`OnOutcome called %d times, want %d`, len(seen), len(outs))
+	}
+	for i, out := range outs {
+		if seen[i] != out {
+			t.Errorf(`OnOutcome call #%d got %v, want %v`, i, seen[i], out)
+		}
+	}
+	if len(outs[0].Info()) != 1 {
+		t.Errorf(`OnOutcome should be called after the stack trace is captured`)
+	}
+}
+
+func TestTryCode(t *testing.T) {
+	for name, test := range map[string]struct {
+		out   *Outcome
+		level int8
+		code  int
+	}{
+		"goodFunc":  {TryCode(17, func() {}), OK, 0},
+		"errFunc":   {TryCode(17, func() error { return fmt.Errorf("test") }), OK, 0},
+		"panicFunc": {TryCode(17, func() { panic("test") }), PANIC, 17},
+		"badFunc":   {TryCode(17, 17), ERROR, 17},
+		"option":    {Try(func() { panic("test") }, WithCode(18)), PANIC, 18},
+		"recover": {func() (out *Outcome) {
+			defer Recover(&out, WithCode(19))
+			panic("test")
+		}(), PANIC, 19},
+	} {
+		if ol, oc := test.out.Level(), test.out.Code(); ol != test.level || oc != test.code {
+			t.Errorf(`TryCode(17, %s) = (%q, 0x%04x), want (%q, 0x%04x)`, name, LevelName(ol), oc, LevelName(test.level), test.code)
+		}
+	}
+
+	opts := make([]Option, 1, 2)
+	opts[0] = WithoutStack()
+	TryCode(17, func() { panic("test") }, opts...)
+	if extra := opts[:2][1]; extra != nil {
+		t.Errorf(`TryCode(17, f, opts...) stored an option in the spare capacity of opts`)
+	}
+}
+
+func TestAddInfoSkip(t *testing.T) {
+	wrapper := func(o *Outcome) *Outcome {
+		return o.AddInfoSkip(1, "debug.stack")
+	}
+	for name, info := range map[string][]string{
+		"AddInfo":     (&Outcome{}).AddInfo("debug.stack").Info(),
+		"AddInfoSkip": wrapper(&Outcome{}).Info(),
+	} {
+		if len(info) != 1 {
+			t.Errorf(`len(%s("debug.stack").Info()) = %d, want %d`, name, len(info), 1)
+			continue
+		}
+		lines := strings.SplitN(info[0], "\n", 3)
+		if len(lines) < 2 || !strings.HasPrefix(lines[1], "github.com/agext/calmly.TestAddInfoSkip(") {
+			t.Errorf(`%s("debug.stack").Info()[0] does not start at the test function (got %q)`, name, info[0])
+		}
+	}
+}
+
+func TestFormatPanic(t *testing.T) {
+	defer func(f func(interface{}) string) {
+		FormatPanic = f
+	}(FormatPanic)
+	FormatPanic = func(recovered interface{}) string {
+		if e, ok := recovered.(testPanicError); ok {
+			return "custom: " + e.reason
+		}
+		return fmt.Sprintf("other: %v", recovered)
+	}
+	for _, test := range []struct {
+		v    interface{}
+		text string
+	}{
+		{testPanicError{"test"}, "custom: test"},
+		{17, "other: 17"},
+	} {
+		out := Try(func() { panic(test.v) })
+		if ot := out.Text(); ot != test.text {
+			t.Errorf(`Try(panic(%#v)).Text() = %q, want %q`, test.v, ot, test.text)
+		}
+		if opv := out.PanicValue(); opv != test.v {
+			t.Errorf(`Try(panic(%#v)).PanicValue() = %#v, want %#v`, test.v, opv, test.v)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	for _, test := range []struct {
+		out *Outcome
+		exp string
+	}{
+		{&Outcome{}, "OK"},
+		{&Outcome{val: 17, text: "ignored"}, "OK"},
+		{&Outcome{level: WARN, text: "abc"}, "abc"},
+		{&Outcome{level: ERROR, code: 17, text: "abc"}, "abc (code: 0x0011)"},
+	} {
+		if got := test.out.String(); got != test.exp {
+			t.Errorf(`%#v.String() = %q, want %q`, test.out, got, test.exp)
+		}
+	}
+	if oe := (&Outcome{}).Error(); oe != "" {
+		t.Errorf(`default.Error() = %q, want %q`, oe, "")
+	}
+}
+
+func TestNestedTry(t *testing.T) {
+	var inner *Outcome
+	out := Try(func() {
+		inner = Try(func() { panic("inner") }).SetCode(17).AddInfo("enriched").WithField("req", 17)
+		inner.Panic()
+	})
+	if of := out.Fields(); !reflect.DeepEqual(of, map[string]interface{}{"req": 17}) {
+		t.Errorf(`Try(inner.Panic).Fields() = %v, want %v`, of, map[string]interface{}{"req": 17})
+	}
+	if ol, oc := out.Level(), out.Code(); ol != PANIC || oc != 17 {
+		t.Errorf(`Try(inner.Panic) = (%q, 0x%04x), want (%q, 0x%04x)`, LevelName(ol), oc, LevelName(PANIC), 17)
+	}
+	if ot := out.Text(); ot != "panic: inner" {
+		t.Errorf(`Try(inner.Panic).Text() = %q, want %q`, ot, "panic: inner")
+	}
+	if oi, ii := out.Info(), inner.Info(); len(oi) != 2 || oi[0] != ii[0] || oi[1] != "enriched" {
+		t.Errorf(`Try(inner.Panic).Info() = %q, want %q`, oi, ii)
+	}
+	if len(out.pcs) == 0 || &out.pcs[0] != &inner.pcs[0] {
+		t.Errorf(`Try(inner.Panic) should keep the program counters captured by the inner Try`)
+	}
+	if opv := out.PanicValue(); opv != "inner" {
+		t.Errorf(`Try(inner.Panic).PanicValue() = %v, want %v`, opv, "inner")
+	}
+
+	out = Try(func() {
+		(&Outcome{level: ERROR, text: "manual"}).Panic()
+	})
+	if ot := out.Text(); ot != "manual" {
+		t.Errorf(`Try(manual.Panic).Text() = %q, want %q`, ot, "manual")
+	}
+	if oi := out.Info(); len(oi) != 1 || !strings.Contains(oi[0], "calmly.TestNestedTry") {
+		t.Errorf(`Try(manual.Panic).Info() does not contain stack trace (got %q)`, oi)
+	}
+	if len(out.pcs) == 0 {
+		t.Errorf(`Try(manual.Panic) should capture the program counters`)
+	}
+
+	out = Try(func() {
+		Must(Wrap(io.EOF).SetCode(42).AddInfo("wrapped").WithField("k", 1))
+	})
+	if ol, oc, oe := out.Level(), out.Code(), out.Error(); ol != PANIC || oc != 42 || oe != "EOF (code: 0x002a)" {
+		t.Errorf(`Try(Must(wrapped)) = (%q, 0x%04x, %q), want (%q, 0x%04x, %q)`, LevelName(ol), oc, oe, LevelName(PANIC), 42, "EOF (code: 0x002a)")
+	}
+	if of := out.Fields(); !reflect.DeepEqual(of, map[string]interface{}{"k": 1}) {
+		t.Errorf(`Try(Must(wrapped)).Fields() = %v, want %v`, of, map[string]interface{}{"k": 1})
+	}
+	if oi := out.Info(); len(oi) != 2 || oi[0] != "wrapped" || !strings.Contains(oi[1], "calmly.TestNestedTry") {
+		t.Errorf(`Try(Must(wrapped)).Info() = %q, want the wrapped info followed by a stack trace`, oi)
+	}
+	if oe := out.Err(); oe != io.EOF {
+		t.Errorf(`Try(Must(wrapped)).Err() = %v, want %v`, oe, io.EOF)
+	}
+
+	out = Try(func() {
+		Must(TryTimeout(time.Millisecond, func() { time.Sleep(time.Second) }))
+	})
+	if !out.HasCode(ERR_TRY_TIMEOUT) {
+		t.Errorf(`Try(Must(TryTimeout(1ms, slowFunc))).Code() = 0x%04x, want 0x%04x`, out.Code(), ERR_TRY_TIMEOUT)
+	}
+}
+
+func TestCaptureStack(t *testing.T) {
+	panicFunc := func() {
+		panic("test")
+	}
+	assertNoStack := func(out *Outcome, action string) {
+		if ol, oc, ot := out.Level(), out.Code(), out.Text(); ol != PANIC || oc != ERR_TRY_PANIC || ot != "panic: test" {
+			t.Errorf(action+` = (%q, 0x%04x, %q), want (%q, 0x%04x, %q)`, LevelName(ol), oc, ot, LevelName(PANIC), ERR_TRY_PANIC, "panic: test")
+		}
+		if info := out.Info(); len(info) != 0 {
+			t.Errorf(`len(`+action+`.Info()) = %d, want %d`, len(info), 0)
+		}
+		if len(out.Frames()) == 0 {
+			t.Errorf(`%s.Frames() should still be available`, action)
+		}
+	}
+	assertNoStack(Try(panicFunc, WithoutStack()), `Try(panicFunc, WithoutStack())`)
+
+	CaptureStack = false
+	defer func() {
+		CaptureStack = true
+	}()
+	assertNoStack(Try(panicFunc), `Try(panicFunc) with CaptureStack = false`)
+}
+
+func BenchmarkTryPanic(b *testing.B) {
+	panicFunc := func() {
+		panic("test")
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Try(panicFunc)
+	}
+}
+
+func BenchmarkTryOK(b *testing.B) {
+	goodFunc := func() {}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Try(goodFunc)
+	}
+}
+
+func BenchmarkTryFastOK(b *testing.B) {
+	goodFunc := func() {}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		TryFast(goodFunc)
+	}
+}
+
+func BenchmarkRecover(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		func() (o *Outcome) {
+			defer Recover(&o, WithoutStack())
+			panic("test")
+		}()
+	}
+}
+
+func BenchmarkRecoverPooled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		func() (o *Outcome) {
+			o = Acquire()
+			defer Recover(&o, WithoutStack())
+			panic("test")
+		}().Release()
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	out := Acquire()
+	if ol := out.Level(); ol != OK {
+		t.Errorf(`Acquire().Level() = %q, want %q`, LevelName(ol), LevelName(OK))
+	}
+	func() {
+		defer Recover(&out)
+		panic("test")
+	}()
+	if ol := out.Level(); ol != PANIC {
+		t.Errorf(`Acquire() with deferred Recover(&o) = %q, want %q`, LevelName(ol), LevelName(PANIC))
+	}
+	out.Release()
+	if ol, ot, oi := out.Level(), out.Text(), out.Info(); ol != OK || ot != "" || len(oi) != 0 {
+		t.Errorf(`Release() left (%q, %q, %q), want a reset Outcome`, LevelName(ol), ot, oi)
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	for _, test := range []struct {
+		level                         int8
+		isOK, isError, isPanic, isFat bool
+	}{
+		{OK, true, false, false, false},
+		{INFO, false, false, false, false},
+		{WARN, false, false, false, false},
+		{ERROR, false, true, false, false},
+		{PANIC, false, true, true, false},
+		{FATAL, false, true, false, true},
+	} {
+		out := &Outcome{level: test.level}
+		if out.IsOK() != test.isOK || out.IsError() != test.isError || out.IsPanic() != test.isPanic || out.IsFatal() != test.isFat {
+			t.Errorf(`%s: (IsOK, IsError, IsPanic, IsFatal) = (%v, %v, %v, %v), want (%v, %v, %v, %v)`, LevelName(test.level),
+				out.IsOK(), out.IsError(), out.IsPanic(), out.IsFatal(), test.isOK, test.isError, test.isPanic, test.isFat)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	out := &Outcome{level: ERROR, text: "abc", info: []string{"line 1"}}
+	if of := out.Fields(); len(of) != 0 {
+		t.Errorf(`default.Fields() = %v, want none`, of)
+	}
+	out.WithField("user", "joe").WithField("request", 17).WithField("user", "jane")
+	of := out.Fields()
+	if len(of) != 2 || of["user"] != "jane" || of["request"] != 17 {
+		t.Errorf(`WithField(...).Fields() = %v, want %v`, of, map[string]interface{}{"user": "jane", "request": 17})
+	}
+	if got, exp := fmt.Sprintf("%+v", out), "abc\nrequest: 17\nuser: jane\nline 1"; got != exp {
+		t.Errorf(`fmt.Sprintf("%%+v", out) = %q, want %q`, got, exp)
+	}
+	c := out.Clone().WithField("user", "joe")
+	if of["user"] != "jane" || c.Fields()["user"] != "joe" {
+		t.Errorf(`Clone().WithField() should not affect the original`)
+	}
+}
+
+func TestAllGoroutines(t *testing.T) {
+	block := make(chan bool)
+	defer close(block)
+	go func() {
+		<-block
+	}()
+	panicFunc := func() {
+		panic("test")
+	}
+	if info := Try(panicFunc).Info(); len(info) != 1 || strings.Count(info[0], "\n\ngoroutine ") != 0 || !strings.Contains(info[0], "calmly.TestAllGoroutines") {
+		t.Errorf(`Try(panicFunc).Info() should contain the current goroutine only (got %q)`, info)
+	}
+
+	AllGoroutines = true
+	defer func() {
+		AllGoroutines = false
+	}()
+	if info := Try(panicFunc).Info(); len(info) != 1 || strings.Count(info[0], "\n\ngoroutine ") < 1 || !strings.HasPrefix(info[0], "goroutine ") {
+		t.Errorf(`Try(panicFunc).Info() with AllGoroutines should contain other goroutines (got %q)`, info)
+	}
+}
+
+func TestCatchType(t *testing.T) {
+	out := Try(func() { panic(&testPanicError{"test"}) })
+	var caught []string
+	out.CatchType("", func(*Outcome) {
+		caught = append(caught, "string")
+	}).CatchType((*testPanicError)(nil), func(o *Outcome) {
+		caught = append(caught, "*testPanicError:"+o.PanicValue().(*testPanicError).reason)
+	}).CatchType(testPanicError{}, func(*Outcome) {
+		caught = append(caught, "testPanicError")
+	}).CatchType((*error)(nil), func(*Outcome) {
+		caught = append(caught, "error")
+	}).CatchType((*fmt.Stringer)(nil), func(*Outcome) {
+		caught = append(caught, "fmt.Stringer")
+	}).CatchType(nil, func(*Outcome) {
+		caught = append(caught, "nil")
+	})
+	if got, exp := strings.Join(caught, ","), "*testPanicError:test,error"; got != exp {
+		t.Errorf(`CatchType chain caught %q, want %q`, got, exp)
+	}
+
+	caught = nil
+	Try(func() {}).CatchType((*error)(nil), func(*Outcome) {
+		caught = append(caught, "error")
+	})
+	if len(caught) != 0 {
+		t.Errorf(`Try(goodFunc).CatchType(...) should not call f`)
+	}
+	out.Handled().CatchType((*error)(nil), func(*Outcome) {
+		caught = append(caught, "error")
+	})
+	if len(caught) != 0 {
+		t.Errorf(`Handled().CatchType(...) should not call f`)
+	}
+}
+
+func TestReset(t *testing.T) {
+	out := Try(func() error {
+		panic("test")
+	}, WithGoroutineCount()).AddInfo("line").WithField("id", 17)
+	capacity := cap(out.info)
+	if ret := out.Reset(); ret != out {
+		t.Errorf(`Reset() should return its receiver`)
+	}
+	if !reflect.DeepEqual(*out, Outcome{info: out.info, stacks: out.stacks, fields: out.fields}) {
+		t.Errorf(`Reset() left state behind: %#v`, *out)
+	}
+	if len(out.Info()) != 0 || cap(out.info) != capacity || out.fields == nil || len(out.Fields()) != 0 {
+		t.Errorf(`Reset() should empty info and fields while retaining their capacity`)
+	}
+	if out.String() != "OK" {
+		t.Errorf(`Reset().String() = %q, want %q`, out.String(), "OK")
+	}
+}
+
+func TestLogAbove(t *testing.T) {
+	log := &mockLogger{}
+	out := &Outcome{text: "abc"}
+	out.LogAbove(OK, log).SetLevel(WARN).LogAbove(FATAL, log).SetLevel(ERROR).LogAbove(FATAL, log).LogAbove(ERROR, log).KeepCalm().SetLevel(FATAL).LogAbove(FATAL, log)
+	if log.log != "abc\n[FATAL] abc\n" {
+		t.Errorf(`logging test got %q, want %q`, log.log, "abc\n[FATAL] abc\n")
+	}
+}
+
+func TestTap(t *testing.T) {
+	var levels []string
+	tap := func(o *Outcome) {
+		levels = append(levels, LevelName(o.Level()))
+	}
+	out := Try(func() { panic("test") })
+	if ret := out.Tap(tap).KeepCalm().Tap(tap).SetLevel(PANIC).Escalate().Tap(tap); ret != out {
+		t.Errorf(`Tap(f) should return its receiver`)
+	}
+	if got, exp := strings.Join(levels, ","), "PANIC,ERROR,FATAL"; got != exp {
+		t.Errorf(`Tap(f) chain observed levels %q, want %q`, got, exp)
+	}
+}
+
+func TestPanicErr(t *testing.T) {
+	out := Try(func() (interface{}, error) {
+		panic(io.EOF)
+	})
+	if ov, oe := out.Result(); ov != nil || oe != io.EOF {
+		t.Errorf(`Try(panicErrFunc).Result() = (%v, %v), want (%v, %v)`, ov, oe, nil, io.EOF)
+	}
+	if !errors.Is(out, io.EOF) {
+		t.Errorf(`errors.Is(Try(panicErrFunc), io.EOF) = false, want true`)
+	}
+	if oe := Try(func() { panic("test") }).Err(); oe != nil {
+		t.Errorf(`Try(panicFunc).Err() = %v, want %v`, oe, nil)
+	}
+
+	out = &Outcome{err: io.ErrUnexpectedEOF}
+	func() {
+		defer Recover(&out)
+		panic(io.EOF)
+	}()
+	if oe := out.Err(); oe != io.ErrUnexpectedEOF {
+		t.Errorf(`Recover(&out).Err() = %v, want the error already stored (%v)`, oe, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	if od := (&Outcome{}).Duration(); od != 0 {
+		t.Errorf(`default.Duration() = %s, want %s`, od, time.Duration(0))
+	}
+	for _, test := range []struct {
+		name string
+		f    func()
+	}{
+		{"sleepFunc", func() { time.Sleep(10 * time.Millisecond) }},
+		{"sleepPanicFunc", func() { time.Sleep(10 * time.Millisecond); panic("test") }},
+	} {
+		out := Try(test.f)
+		if od := out.Duration(); od < 10*time.Millisecond || od > time.Second {
+			t.Errorf(`Try(%s).Duration() = %s, want about %s`, test.name, od, 10*time.Millisecond)
+		}
+		if got, exp := fmt.Sprintf("%+v", out), fmt.Sprintf("\nduration: %s", out.Duration()); !strings.HasSuffix(got, exp) {
+			t.Errorf(`fmt.Sprintf("%%+v", Try(%s)) does not end with %q (got %q)`, test.name, exp, got)
+		}
+	}
+}
+
+func TestEscalateIf(t *testing.T) {
+	var calls int
+	isCode := func(code int) func(*Outcome) bool {
+		return func(o *Outcome) bool {
+			calls++
+			return o.Code() == code
+		}
+	}
+	if ol := Try(func() {}).EscalateIf(isCode(0)).KeepCalmIf(isCode(0)).Level(); ol != OK || calls != 0 {
+		t.Errorf(`Try(goodFunc).EscalateIf(pred).KeepCalmIf(pred) = %q with %d predicate calls, want %q with none`, LevelName(ol), calls, LevelName(OK))
+	}
+	panicFunc := func() { panic("test") }
+	if ol := Try(panicFunc).EscalateIf(isCode(17)).Level(); ol != PANIC {
+		t.Errorf(`Try(panicFunc).EscalateIf(isCode(17)).Level() = %q, want %q`, LevelName(ol), LevelName(PANIC))
+	}
+	if ol := Try(panicFunc).EscalateIf(isCode(ERR_TRY_PANIC)).Level(); ol != FATAL {
+		t.Errorf(`Try(panicFunc).EscalateIf(isCode(ERR_TRY_PANIC)).Level() = %q, want %q`, LevelName(ol), LevelName(FATAL))
+	}
+	if ol := Try(panicFunc).EscalateIf(isCode(17)).KeepCalmIf(isCode(ERR_TRY_PANIC)).Level(); ol != ERROR {
+		t.Errorf(`Try(panicFunc).EscalateIf(isCode(17)).KeepCalmIf(isCode(ERR_TRY_PANIC)).Level() = %q, want %q`, LevelName(ol), LevelName(ERROR))
+	}
+	if ol := Try(panicFunc).KeepCalmIf(isCode(17)).Level(); ol != PANIC {
+		t.Errorf(`Try(panicFunc).KeepCalmIf(isCode(17)).Level() = %q, want %q`, LevelName(ol), LevelName(PANIC))
+	}
+}
+
+func TestTrimPath(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	dir := file[:strings.LastIndex(file, "/")+1]
+	assertTrimmed := func(out *Outcome, action string) {
+		if len(out.info) != 1 {
+			t.Fatalf(`len(%s.Info()) = %d, want %d`, action, len(out.info), 1)
+		}
+		st := out.info[0]
+		if strings.Contains(st, "\t"+dir) {
+			t.Errorf(`%s.Info()[0] still contains %q (got %q)`, action, dir, st)
+		}
+		// the panic frame comes first, followed by the panicking function
+		lines := strings.SplitN(st, "\n", 6)
+		if len(lines) < 6 || !strings.Contains(lines[3], "calmly.TestTrimPath") || !strings.HasPrefix(lines[4], "\tcalmly_test.go:") {
+			t.Errorf(`%s.Info()[0] does not list the trimmed panicking frame first (got %q)`, action, st)
+		}
+	}
+
+	panicFunc := func() { panic("test") }
+	assertTrimmed(Try(panicFunc).Sanitize(dir), "Try(panicFunc).Sanitize(dir)")
+	note := "config:\n\t" + dir + "app.conf"
+	if oi := Try(panicFunc).AddInfo(note).Sanitize(dir).Info(); len(oi) != 2 || oi[1] != note {
+		t.Errorf(`Try(panicFunc).AddInfo(note).Sanitize(dir).Info() = %q, want the stack trace and %q unchanged`, oi, note)
+	}
+
+	defer func(tp string) { TrimPath = tp }(TrimPath)
+	TrimPath = dir
+	assertTrimmed(Try(panicFunc), "Try(panicFunc) with TrimPath")
+}
+
+func TestHasCode(t *testing.T) {
+	if Try(func() {}).HasCode(ERR_TRY_ARG) {
+		t.Errorf(`Try(goodFunc).HasCode(ERR_TRY_ARG) = true, want false`)
+	}
+	if !Try(17).HasCode(ERR_TRY_ARG) {
+		t.Errorf(`Try(17).HasCode(ERR_TRY_ARG) = false, want true`)
+	}
+	out := Try(func() { panic("test") })
+	if !out.HasCode(ERR_TRY_PANIC) {
+		t.Errorf(`Try(panicFunc).HasCode(ERR_TRY_PANIC) = false, want true`)
+	}
+	if out.HasCode(17) {
+		t.Errorf(`Try(panicFunc).HasCode(17) = true, want false`)
+	}
+	if !TryCode(17, func() { panic("test") }).HasCode(17) {
+		t.Errorf(`TryCode(17, panicFunc).HasCode(17) = false, want true`)
+	}
+}
+
+func TestHandled(t *testing.T) {
+	if out := Try(func() {}).Handled(); out.IsHandled() {
+		t.Errorf(`Try(goodFunc).Handled().IsHandled() = true, want false`)
+	}
+	log := &mockLogger{}
+	out := Try(func() { panic("test") }).Catch(func(o *Outcome) {
+		if o.IsHandled() {
+			t.Errorf(`Try(panicFunc).IsHandled() = true, want false`)
+		}
+		o.Handled()
+	}).Log(log)
+	if !out.IsHandled() || out.IsError() || out.Level() != OK {
+		t.Errorf(`Try(panicFunc).Catch(handle) = (%q, handled: %t), want (%q, handled: true)`, LevelName(out.Level()), out.IsHandled(), LevelName(OK))
+	}
+	if out.Code() != ERR_TRY_PANIC || out.Text() != "panic: test" {
+		t.Errorf(`Try(panicFunc).Catch(handle) = (0x%04x, %q), want the details preserved`, out.Code(), out.Text())
+	}
+	if log.log != "" {
+		t.Errorf(`Try(panicFunc).Catch(handle).Log() logged %q, want nothing`, log.log)
+	}
+}
+
+func TestOnPanicInfo(t *testing.T) {
+	state := "idle"
+	dump := OnPanicInfo(func() []string {
+		return []string{"state: " + state}
+	})
+	if out := Try(func() { state = "busy" }, dump); len(out.Info()) != 0 {
+		t.Errorf(`Try(goodFunc, OnPanicInfo(dump)).Info() = %q, want none`, out.Info())
+	}
+	out := Try(func() {
+		state = "working"
+		panic("test")
+	}, WithoutStack(), dump, OnPanicInfo(func() []string { panic("oops") }))
+	if got, exp := out.Info(), []string{"state: working", "OnPanicInfo: panic: oops"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf(`Try(panicFunc, OnPanicInfo(dump), OnPanicInfo(panicking)).Info() = %q, want %q`, got, exp)
+	}
+	if ot := out.Text(); ot != "panic: test" {
+		t.Errorf(`Try(panicFunc, OnPanicInfo(dump)).Text() = %q, want %q`, ot, "panic: test")
+	}
+}
+
+func TestSetValueErr(t *testing.T) {
+	out := (&Outcome{}).SetValue(17).SetErr(io.EOF)
+	if ov, oe := out.Result(); ov != 17 || oe != io.EOF {
+		t.Errorf(`SetValue(17).SetErr(io.EOF).Result() = (%v, %v), want (%v, %v)`, ov, oe, 17, io.EOF)
+	}
+	if ol := out.Level(); ol != OK {
+		t.Errorf(`SetErr(io.EOF).Level() = %q, want %q`, LevelName(ol), LevelName(OK))
+	}
+	if ov, oe := out.SetValue(nil).SetErr(nil).Result(); ov != nil || oe != nil {
+		t.Errorf(`SetValue(nil).SetErr(nil).Result() = (%v, %v), want (%v, %v)`, ov, oe, nil, nil)
+	}
+}
+
+func TestThen(t *testing.T) {
+	var steps []string
+	step := func(name string) func() {
+		return func() { steps = append(steps, name) }
+	}
+	if ol := Try(step("a")).Then(step("b")).Then(step("c")).Level(); ol != OK || strings.Join(steps, "") != "abc" {
+		t.Errorf(`Try(a).Then(b).Then(c) = %q after steps %q, want %q after steps %q`, LevelName(ol), steps, LevelName(OK), "abc")
+	}
+
+	steps = nil
+	failed := Try(step("a")).Then(func() { panic("test") })
+	if out := failed.Then(step("c")); out != failed || out.Level() != PANIC || strings.Join(steps, "") != "a" {
+		t.Errorf(`Try(a).Then(panicFunc).Then(c) should return the PANIC Outcome without running c (steps %q)`, steps)
+	}
+	errored := Try(func() error { return io.EOF })
+	if out := errored.Then(step("c")); out != errored || len(steps) != 1 {
+		t.Errorf(`Try(errFunc).Then(c) should return its receiver without running c (steps %q)`, steps)
+	}
+
+	out := Try(func() interface{} { return 17 }).ThenWith(func(prev interface{}) (interface{}, error) {
+		return prev.(int) + 1, nil
+	}).ThenWith(func(prev interface{}) (interface{}, error) {
+		return prev.(int) * 2, nil
+	})
+	if ov, oe := out.Result(); ov != 36 || oe != nil {
+		t.Errorf(`Try(17).ThenWith(inc).ThenWith(double).Result() = (%v, %v), want (%v, %v)`, ov, oe, 36, nil)
+	}
+	if ol := Try(func() {}).ThenWith(func(prev interface{}) (interface{}, error) { return prev.(int), nil }).Level(); ol != PANIC {
+		t.Errorf(`Try(goodFunc).ThenWith(badAssertion).Level() = %q, want %q`, LevelName(ol), LevelName(PANIC))
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	ordered := []int8{OK, INFO, WARN, ERROR, PANIC, FATAL}
+	for i, a := range ordered {
+		for j, b := range ordered {
+			if got := SeverityLess(a, b); got != (i < j) {
+				t.Errorf(`SeverityLess(%s, %s) = %t, want %t`, LevelName(a), LevelName(b), got, i < j)
+			}
+			if got := (&Outcome{level: a}).AtLeast(b); got != (i >= j) {
+				t.Errorf(`%s.AtLeast(%s) = %t, want %t`, LevelName(a), LevelName(b), got, i >= j)
+			}
+		}
+	}
+}
+
+type testErrPtr struct{}
+
+func (*testErrPtr) Error() string { return "testErrPtr" }
+
+func TestTryReflective(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		f     interface{}
+		level int8
+		val   interface{}
+		err   error
+	}{
+		{"valErr", func() (int, error) { return 17, io.EOF }, OK, 17, io.EOF},
+		{"errVal", func() (error, string) { return io.EOF, "abc" }, OK, "abc", io.EOF},
+		{"val", func() int { return 17 }, OK, 17, nil},
+		{"errPtr", func() *testErrPtr { return nil }, OK, nil, nil},
+		{"valErrPtr", func() (int, *testErrPtr) { return 17, &testErrPtr{} }, OK, 17, &testErrPtr{}},
+		{"twoVals", func() (int, int) { return 1, 2 }, ERROR, nil, nil},
+		{"twoErrs", func() (error, error) { return nil, nil }, ERROR, nil, nil},
+		{"threeResults", func() (int, int, error) { return 1, 2, nil }, ERROR, nil, nil},
+		{"withArg", func(int) {}, ERROR, nil, nil},
+	} {
+		out := Try(test.f)
+		if ol := out.Level(); ol != test.level {
+			t.Errorf(`Try(%s).Level() = %q, want %q`, test.name, LevelName(ol), LevelName(test.level))
+		}
+		ov, oe := out.Result()
+		if ov != test.val || (oe == nil) != (test.err == nil) || oe != nil && oe.Error() != test.err.Error() {
+			t.Errorf(`Try(%s).Result() = (%v, %v), want (%v, %v)`, test.name, ov, oe, test.val, test.err)
+		}
+	}
+	if ol := Try(func() (int, error) { panic("test") }).Level(); ol != PANIC {
+		t.Errorf(`Try(panicValErr).Level() = %q, want %q`, LevelName(ol), LevelName(PANIC))
+	}
+}
+
+func TestErrorFormat(t *testing.T) {
+	defer func(ef func(*Outcome) string) { ErrorFormat = ef }(ErrorFormat)
+	ErrorFormat = func(o *Outcome) string {
+		return fmt.Sprintf("[%s] %s #%d", LevelName(o.level), o.text, o.code)
+	}
+	out := Try(func() { panic("test") })
+	if oe, exp := out.Error(), "[PANIC] panic: test #1"; oe != exp {
+		t.Errorf(`Try(panicFunc).Error() = %q, want %q`, oe, exp)
+	}
+	if os, exp := fmt.Sprint(out), "[PANIC] panic: test #1"; os != exp {
+		t.Errorf(`fmt.Sprint(Try(panicFunc)) = %q, want %q`, os, exp)
+	}
+	if os := Try(func() {}).String(); os != "OK" {
+		t.Errorf(`Try(goodFunc).String() = %q, want %q`, os, "OK")
+	}
+	ErrorFormat = nil
+	if oe, exp := out.Error(), "panic: test (code: 0x0001)"; oe != exp {
+		t.Errorf(`Try(panicFunc).Error() with nil ErrorFormat = %q, want %q`, oe, exp)
+	}
+}
+
+func TestRecoverErr(t *testing.T) {
+	protected := func(p interface{}) (err error) {
+		defer RecoverErr(&err)
+		if p != nil {
+			panic(p)
+		}
+		return io.ErrUnexpectedEOF
+	}
+	if err := protected(nil); err != io.ErrUnexpectedEOF {
+		t.Errorf(`protected(nil) = %v, want %v`, err, io.ErrUnexpectedEOF)
+	}
+	err := protected(io.EOF)
+	if es, exp := fmt.Sprint(err), "panic: EOF (code: 0x0001)"; es != exp {
+		t.Errorf(`protected(io.EOF) = %q, want %q`, es, exp)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Errorf(`errors.Is(protected(io.EOF), io.EOF) = false, want true`)
+	}
+	var out *Outcome
+	if !errors.As(err, &out) {
+		t.Fatalf(`errors.As(protected(io.EOF), &out) = false, want true`)
+	}
+	if info := out.Info(); len(info) != 1 {
+		t.Errorf(`len(RecoverErr(&err).Info()) = %d, want %d`, len(info), 1)
+	} else if strings.Contains(info[0], "calmly.RecoverErr") || !strings.Contains(info[0], "calmly.TestRecoverErr") {
+		t.Errorf(`RecoverErr(&err).Info()[0] does not start at the protected function (got %q)`, info[0])
+	}
+	if errors.Is(protected("test"), io.EOF) {
+		t.Errorf(`errors.Is(protected("test"), io.EOF) = true, want false`)
+	}
+}
+
+func TestPanicNil(t *testing.T) {
+	out := Try(func() {
+		panic(nil)
+	})
+	if ol, oc, ot := out.Level(), out.Code(), out.Text(); ol != PANIC || oc != ERR_TRY_PANIC || ot != "panic: nil" {
+		t.Errorf(`Try(panicNilFunc) = (%q, 0x%04x, %q), want (%q, 0x%04x, %q)`, LevelName(ol), oc, ot, LevelName(PANIC), ERR_TRY_PANIC, "panic: nil")
+	}
+	if info := out.Info(); len(info) != 1 || !strings.Contains(info[0], "calmly.TestPanicNil") {
+		t.Errorf(`Try(panicNilFunc).Info() does not contain the stack trace (got %q)`, info)
+	}
+	if ol := Try(func() error { return nil }).Level(); ol != OK {
+		t.Errorf(`Try(nilErrFunc).Level() = %q, want %q`, LevelName(ol), LevelName(OK))
+	}
+}
+
+type testStatus int
+
+func TestTryWith(t *testing.T) {
+	mapper := func(recovered interface{}) *Outcome {
+		if s, ok := recovered.(testStatus); ok {
+			level := ERROR
+			if s >= 500 {
+				level = FATAL
+			}
+			return (&Outcome{level: level, code: int(s), text: fmt.Sprintf("status %d", s)}).WithField("status", int(s))
+		}
+		return nil
+	}
+	out := TryWith(mapper, func() { panic(testStatus(404)) })
+	if ol, oc, ot := out.Level(), out.Code(), out.Text(); ol != ERROR || oc != 404 || ot != "status 404" {
+		t.Errorf(`TryWith(mapper, panic404) = (%q, %d, %q), want (%q, %d, %q)`, LevelName(ol), oc, ot, LevelName(ERROR), 404, "status 404")
+	}
+	if info, pv := out.Info(), out.PanicValue(); len(info) != 1 || !strings.Contains(info[0], "calmly.TestTryWith") || pv != testStatus(404) {
+		t.Errorf(`TryWith(mapper, panic404) = (%q, %v), want the stack trace and panic value`, info, pv)
+	}
+	if of := out.Fields(); of["status"] != 404 {
+		t.Errorf(`TryWith(mapper, panic404).Fields() = %v, want the status`, of)
+	}
+	if ol := TryWith(mapper, func() { panic(testStatus(503)) }).Level(); ol != FATAL {
+		t.Errorf(`TryWith(mapper, panic503).Level() = %q, want %q`, LevelName(ol), LevelName(FATAL))
+	}
+	out = TryWith(mapper, func() { panic("test") })
+	if ol, oc, ot := out.Level(), out.Code(), out.Text(); ol != PANIC || oc != ERR_TRY_PANIC || ot != "panic: test" {
+		t.Errorf(`TryWith(mapper, panicFunc) = (%q, 0x%04x, %q), want the default Outcome`, LevelName(ol), oc, ot)
+	}
+	out = TryWith(func(interface{}) *Outcome { panic("oops") }, func() { panic("test") }, WithoutStack())
+	if ol, info := out.Level(), out.Info(); ol != PANIC || len(info) != 1 || info[0] != "TryWith: mapper panic: oops" {
+		t.Errorf(`TryWith(panickingMapper, panicFunc) = (%q, %q), want the default Outcome and the mapper panic`, LevelName(ol), info)
+	}
+	if ol := TryWith(mapper, func() {}).Level(); ol != OK {
+		t.Errorf(`TryWith(mapper, goodFunc).Level() = %q, want %q`, LevelName(ol), LevelName(OK))
+	}
+}
+
+func TestAddInfof(t *testing.T) {
+	out := (&Outcome{}).AddInfof("id: %d", 17).AddInfof("debug.%s", "stack")
+	info := out.Info()
+	if len(info) != 2 || info[0] != "id: 17" {
+		t.Fatalf(`AddInfof("id: %%d", 17).AddInfof("debug.%%s", "stack").Info() = %q, want %q and a stack trace`, info, "id: 17")
+	}
+	lines := strings.SplitN(info[1], "\n", 3)
+	if len(lines) < 3 || !strings.Contains(lines[1], "calmly.TestAddInfof") {
+		t.Errorf(`AddInfof("debug.%%s", "stack").Info()[1] does not start at the calling function (got %q)`, info[1])
+	}
+}
+
+func TestEqual(t *testing.T) {
+	panicFunc := func() { panic(io.EOF) }
+	a, b := Try(panicFunc).AddInfo("id: 17"), Try(panicFunc).AddInfo("id: 17")
+	if Equal(a, b) {
+		t.Errorf(`Equal(a, b) = true for Outcomes with different stack traces, want false`)
+	}
+	if !EqualIgnoringStack(a, b) {
+		t.Errorf(`EqualIgnoringStack(a, b) = false, want true`)
+	}
+	if c := a.Clone(); !Equal(a, c) {
+		t.Errorf(`Equal(a, a.Clone()) = false, want true`)
+	}
+	if !Equal(nil, nil) || Equal(a, nil) || Equal(nil, a) {
+		t.Errorf(`Equal does not handle nil Outcomes`)
+	}
+	for name, c := range map[string]*Outcome{
+		"SetLevel":    b.Clone().SetLevel(ERROR),
+		"SetCode":     b.Clone().SetCode(17),
+		"SetText":     b.Clone().SetText("abc"),
+		"SetErr":      b.Clone().SetErr(io.ErrUnexpectedEOF),
+		"SetErr(nil)": b.Clone().SetErr(nil),
+		"AddInfo":     b.Clone().AddInfo("user: jane"),
+	} {
+		if EqualIgnoringStack(a, c) {
+			t.Errorf(`EqualIgnoringStack(a, b.%s(...)) = true, want false`, name)
+		}
+	}
+	if !EqualIgnoringStack(b.Clone().SetErr(fmt.Errorf("EOF")), a) {
+		t.Errorf(`EqualIgnoringStack does not compare errors by message`)
+	}
+}
+
+func TestLogGraceful(t *testing.T) {
+	log := &mockLogger{}
+	out := &Outcome{text: "abc"}
+	for _, level := range []int8{OK, WARN, ERROR, PANIC, FATAL} {
+		ret, err := out.SetLevel(level).LogGraceful(log)
+		if ret != out {
+			t.Errorf(`%s.LogGraceful(log) should return its receiver`, LevelName(level))
+		}
+		if exp := level == PANIC || level == FATAL; (err != nil) != exp || err != nil && err != error(out) {
+			t.Errorf(`%s.LogGraceful(log) returned error %v, want the Outcome: %t`, LevelName(level), err, exp)
+		}
+	}
+	if log.log != "abc\nabc\nabc\nabc\n" {
+		t.Errorf(`graceful logging test got %q, want %q`, log.log, "abc\nabc\nabc\nabc\n")
+	}
+}
+
+func TestStackSampleRate(t *testing.T) {
+	panicFunc := func() { panic("test") }
+	if out := Try(func() {}); out.Sampled() {
+		t.Errorf(`Try(goodFunc).Sampled() = true, want false`)
+	}
+	if out := Try(panicFunc); !out.Sampled() || len(out.Info()) != 1 {
+		t.Errorf(`Try(panicFunc) = (sampled: %t, %d info), want a stack trace`, out.Sampled(), len(out.Info()))
+	}
+	if out := Try(panicFunc, WithoutStack()); out.Sampled() {
+		t.Errorf(`Try(panicFunc, WithoutStack()).Sampled() = true, want false`)
+	}
+
+	defer func(rate int) { StackSampleRate = rate }(StackSampleRate)
+	StackSampleRate = 3
+	stackSamples.Store(0)
+	var sampled []bool
+	for i := 0; i < 7; i++ {
+		out := Try(panicFunc)
+		if out.Sampled() != (len(out.Info()) == 1) || out.Text() != "panic: test" {
+			t.Errorf(`Try(panicFunc).Sampled() = %t with %d info lines and text %q`, out.Sampled(), len(out.Info()), out.Text())
+		}
+		sampled = append(sampled, out.Sampled())
+	}
+	if exp := []bool{true, false, false, true, false, false, true}; !reflect.DeepEqual(sampled, exp) {
+		t.Errorf(`Try(panicFunc).Sampled() with StackSampleRate = 3 = %v, want %v`, sampled, exp)
+	}
+}
+
+func TestTryInto(t *testing.T) {
+	out := (&Outcome{val: 3, err: io.EOF}).AddInfo("stale").WithField("id", 17).SetLevel(FATAL)
+	if ret := TryInto(out, func() interface{} { return 17 }); ret != out {
+		t.Errorf(`TryInto(out, f) should return out`)
+	}
+	if ol, ov, oe, oi, of := out.Level(), out.Value(), out.Err(), out.Info(), out.Fields(); ol != OK || ov != 17 || oe != nil || len(oi) != 0 || len(of) != 0 {
+		t.Errorf(`TryInto(out, valFunc) = (%q, %v, %v, %q, %v), want (%q, %v, %v, none, none)`, LevelName(ol), ov, oe, oi, of, LevelName(OK), 17, nil)
+	}
+	if ret := TryInto(out, func() { panic("test") }); ret != out || out.Level() != PANIC || out.Value() != nil || len(out.Info()) != 1 {
+		t.Errorf(`TryInto(out, panicFunc) = (%p, %q, %v, %d info), want (%p, %q, %v, 1 info)`, ret, LevelName(out.Level()), out.Value(), len(out.Info()), out, LevelName(PANIC), nil)
+	}
+	if ret := TryInto(out, 17); ret != out || out.Code() != ERR_TRY_ARG || len(out.Info()) != 0 {
+		t.Errorf(`TryInto(out, 17) = (%p, 0x%04x, %q), want (%p, 0x%04x, none)`, ret, out.Code(), out.Info(), out, ERR_TRY_ARG)
+	}
+}
+
+func TestAddInfoAliasing(t *testing.T) {
+	lines := []string{"line 1", "debug.stack"}
+	out := (&Outcome{}).AddInfo(lines...)
+	if lines[1] != "debug.stack" {
+		t.Errorf(`AddInfo(lines...) changed lines[1] to %q, want %q`, lines[1], "debug.stack")
+	}
+	if info := out.Info(); len(info) != 2 || !strings.HasPrefix(info[1], "goroutine ") {
+		t.Errorf(`AddInfo(lines...).Info()[1] does not contain stack trace (got %q)`, info)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	out := Try(func() { panic("test") }).AddInfo("id: 17", "goroutine 1 is busy")
+	if om := out.Messages(); !reflect.DeepEqual(om, []string{"id: 17", "goroutine 1 is busy"}) {
+		t.Errorf(`Try(panicFunc).AddInfo(...).Messages() = %q, want %q`, om, []string{"id: 17", "goroutine 1 is busy"})
+	}
+	if oi := out.Info(); len(oi) != 3 || !strings.HasPrefix(oi[0], "goroutine ") {
+		t.Errorf(`Try(panicFunc).AddInfo(...).Info() does not hold the stack trace (got %q)`, oi)
+	}
+	m := Merge(&Outcome{info: []string{"first"}}, out, Try(func() { panic("test") }, OnPanicInfo(func() []string {
+		return []string{"last"}
+	})))
+	if mm := m.Messages(); !reflect.DeepEqual(mm, []string{"first", "id: 17", "goroutine 1 is busy", "last"}) {
+		t.Errorf(`Merge(...).Messages() = %q, want %q`, mm, []string{"first", "id: 17", "goroutine 1 is busy", "last"})
+	}
+	if mm := m.Clone().AddInfo("debug.stack").Messages(); len(mm) != 4 {
+		t.Errorf(`Merge(...).Clone().AddInfo("debug.stack").Messages() = %q, want 4 entries`, mm)
+	}
+	if om := Try(func() {}).Messages(); om != nil {
+		t.Errorf(`Try(goodFunc).Messages() = %q, want %v`, om, nil)
+	}
+}
+
+func TestTryResult(t *testing.T) {
+	if v, err := TryResult(func() (interface{}, error) { return 17, nil }); v != 17 || err != nil {
+		t.Errorf(`TryResult(goodFunc) = (%v, %v), want (%v, %v)`, v, err, 17, nil)
+	}
+	if v, err := TryResult(func() (interface{}, error) { return 17, io.EOF }); v != 17 || err != io.EOF {
+		t.Errorf(`TryResult(errFunc) = (%v, %v), want (%v, %v)`, v, err, 17, io.EOF)
+	}
+	v, err := TryResult(func() (interface{}, error) { panic(io.EOF) }, WithCode(17))
+	var o *Outcome
+	if v != nil || !errors.As(err, &o) || o.Level() != PANIC || !errors.Is(err, io.EOF) {
+		t.Errorf(`TryResult(panicFunc) = (%v, %v), want (%v, the Outcome)`, v, err, nil)
+	}
+	if es := err.Error(); es != "panic: EOF (code: 0x0011)" {
+		t.Errorf(`TryResult(panicFunc) error text = %q, want %q`, es, "panic: EOF (code: 0x0011)")
+	}
+	if es := fmt.Sprintf("%+v", err); !strings.Contains(es, "calmly.TestTryResult") {
+		t.Errorf(`TryResult(panicFunc) error formatted with %%+v does not hold the stack trace (got %q)`, es)
+	}
+}
+
+func TestTimestampHost(t *testing.T) {
+	defer func(h string) { Hostname = h }(Hostname)
+	Hostname = "node-17"
+	before := time.Now()
+	out := Try(func() { panic("test") })
+	if ots, oh := out.Timestamp(), out.Host(); ots.Before(before) || ots.After(time.Now()) || oh != "node-17" {
+		t.Errorf(`Try(panicFunc) = (%v, %q), want (a time since %v, %q)`, ots, oh, before, "node-17")
+	}
+	var rec *Outcome
+	func() {
+		defer Recover(&rec)
+		panic("test")
+	}()
+	if ots, oh := rec.Timestamp(), rec.Host(); ots.Before(out.Timestamp()) || oh != "node-17" {
+		t.Errorf(`Recover(&rec) = (%v, %q), want (a time since %v, %q)`, ots, oh, out.Timestamp(), "node-17")
+	}
+	for name, o := range map[string]*Outcome{
+		"Wrap":      Wrap(io.EOF),
+		"OKOutcome": OKOutcome(17),
+		"Merge":     Merge(out, rec),
+	} {
+		if o.Timestamp().IsZero() || o.Host() != "node-17" {
+			t.Errorf(`%s(...) = (%v, %q), want (the current time, %q)`, name, o.Timestamp(), o.Host(), "node-17")
+		}
+	}
+	if ots := out.Reset().Timestamp(); !ots.IsZero() {
+		t.Errorf(`Reset().Timestamp() = %v, want the zero time`, ots)
+	}
+}
+
+func TestStop(t *testing.T) {
+	var calls []string
+	handler := func(name string, stop bool) func(*Outcome) {
+		return func(o *Outcome) {
+			calls = append(calls, name)
+			if stop {
+				o.Stop()
+			}
+		}
+	}
+	out := Try(func() { panic(io.EOF) }).
+		CatchLevel(ERROR, handler("CatchLevel", true)).
+		CatchType(io.EOF, handler("CatchType", true)).
+		Catch(handler("Catch", false)).
+		CatchAny(handler("CatchAny", false)).
+		OnError(handler("OnError", false)).
+		Finally(handler("Finally", false))
+	if exp := []string{"CatchType", "Finally"}; !reflect.DeepEqual(calls, exp) {
+		t.Errorf(`Catch chain with Stop called %q, want %q`, calls, exp)
+	}
+	if !out.IsStopped() || out.Level() != PANIC {
+		t.Errorf(`Stop() = (%v, %q), want (%v, %q)`, out.IsStopped(), LevelName(out.Level()), true, LevelName(PANIC))
+	}
+	if Try(func() { panic(io.EOF) }).IsStopped() {
+		t.Errorf(`Try(panicFunc).IsStopped() = true, want false`)
+	}
+}
+
+func TestTryFast(t *testing.T) {
+	out := TryFast(func() {})
+	if out != okOutcome || out.Level() != OK {
+		t.Errorf(`TryFast(goodFunc) = %v, want the shared OK Outcome`, out)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { TryFast(func() {}) }); allocs != 0 {
+		t.Errorf(`TryFast(goodFunc) allocates %v times, want none`, allocs)
+	}
+	for name, f := range map[string]interface{}{
+		"valueFunc": func() interface{} { return 17 },
+		"errFunc":   func() error { return io.EOF },
+		"panicFunc": func() { panic("test") },
+	} {
+		if o := TryFast(f); o == okOutcome {
+			t.Errorf(`TryFast(%s) returned the shared OK Outcome`, name)
+		}
+	}
+	if o := TryFast(func() { panic("test") }); o.Level() != PANIC || o.Text() != "panic: test" || o.Timestamp().IsZero() {
+		t.Errorf(`TryFast(panicFunc) = %v, want the same Outcome as Try`, o)
+	}
+
+	// the shared Outcome must survive handling chains unchanged
+	out.Catch(func(o *Outcome) { o.SetText("abc") }).CatchAny(func(o *Outcome) { o.Stop() }).
+		KeepCalm().Escalate().Handled().Reset().Then(func() {}).LogAbove(INFO, &mockLogger{})
+	out.Release()
+	var rec *Outcome = out
+	func() {
+		defer Recover(&rec)
+		panic("test")
+	}()
+	if rec == okOutcome {
+		t.Errorf(`Recover(&sharedOK) updated the shared OK Outcome`)
+	}
+	out.Clone().SetLevel(ERROR).AddInfo("x").WithField("id", 17).Stop()
+	for name, mutate := range map[string]func(){
+		"SetLevel":  func() { out.SetLevel(ERROR) },
+		"SetCode":   func() { out.SetCode(17) },
+		"SetText":   func() { out.SetText("abc") },
+		"AddInfo":   func() { out.AddInfo("x") },
+		"WithField": func() { out.WithField("id", 17) },
+		"SetValue":  func() { out.SetValue(17) },
+		"SetErr":    func() { out.SetErr(io.EOF) },
+		"Stop":      func() { out.Stop() },
+		"TryInto":   func() { TryInto(out, func() {}) },
+	} {
+		if Try(mutate).Level() != PANIC {
+			t.Errorf(`sharedOK.%s(...) should panic`, name)
+		}
+	}
+	if !reflect.DeepEqual(*okOutcome, Outcome{}) {
+		t.Errorf(`the shared OK Outcome was modified: %#v`, *okOutcome)
 	}
 }
@@ -0,0 +1,127 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"fmt"
+)
+
+// firstAllocatedCode is the first code handed out by CodeSpaces, leaving the
+// lower ones to the predefined codes and to RegisterCode.
+const firstAllocatedCode = 0x10000
+
+// CodeSpace allocates error codes on behalf of a library or application, so
+// that several packages building on calmly do not both claim the same code with
+// different meanings. Each code allocated by a CodeSpace is unique across all
+// spaces, and its name is registered like via RegisterCode.
+type CodeSpace struct {
+	name  string
+	codes map[string]int
+}
+
+// DefaultCodeSpace holds the predefined codes, as well as those registered via
+// RegisterCode rather than allocated by another CodeSpace.
+var DefaultCodeSpace = &CodeSpace{name: "calmly"}
+
+// NewCodeSpace returns a new CodeSpace with the provided name, such as the
+// import path of the package using it. It panics if a CodeSpace with that name
+// already exists.
+func NewCodeSpace(name string) *CodeSpace {
+	codes.Lock()
+	defer codes.Unlock()
+	if _, ok := codes.spaces[name]; ok {
+		panic(fmt.Sprintf("calmly: code space %s already exists", name))
+	}
+	cs := &CodeSpace{name: name, codes: map[string]int{}}
+	codes.spaces[name] = cs
+	return cs
+}
+
+// Name returns the name of the receiver.
+func (cs *CodeSpace) Name() string {
+	return cs.name
+}
+
+// Code returns the code allocated by the receiver for the provided name,
+// allocating a new one the first time the name is used. Allocated codes start at
+// 0x10000, skipping any code already registered via RegisterCode.
+// It panics if called on DefaultCodeSpace; use RegisterCode instead.
+//
+// Code is safe for concurrent use, but codes are meant to be allocated during
+// program initialization, e.g. `var ErrQuota = space.Code("ERR_QUOTA")`.
+func (cs *CodeSpace) Code(name string) int {
+	if cs == DefaultCodeSpace {
+		panic("calmly: cannot allocate codes in the default code space")
+	}
+	codes.Lock()
+	defer codes.Unlock()
+	if code, ok := cs.codes[name]; ok {
+		return code
+	}
+	for {
+		code := codes.next
+		codes.next++
+		if _, ok := codes.names[code]; !ok {
+			codes.names[code], codes.owners[code] = name, cs
+			cs.codes[name] = code
+			return code
+		}
+	}
+}
+
+// Lookup returns the name registered for the provided code, if it belongs to
+// the receiver.
+func (cs *CodeSpace) Lookup(code int) (string, bool) {
+	codes.RLock()
+	defer codes.RUnlock()
+	name, ok := codes.names[code]
+	owner := codes.owners[code]
+	if owner == nil {
+		owner = DefaultCodeSpace
+	}
+	if !ok || owner != cs {
+		return "", false
+	}
+	return name, true
+}
+
+// LookupCode returns the name registered for the provided code in the CodeSpace
+// with the provided name, if both exist.
+func LookupCode(space string, code int) (string, bool) {
+	codes.RLock()
+	cs, ok := codes.spaces[space]
+	codes.RUnlock()
+	if !ok {
+		return "", false
+	}
+	return cs.Lookup(code)
+}
+
+// CodeSpace returns the CodeSpace that the error code stored by the receiver
+// belongs to, or nil for OK outcomes and codes that were never registered.
+func (o *Outcome) CodeSpace() *CodeSpace {
+	if o.level == OK {
+		return nil
+	}
+	codes.RLock()
+	defer codes.RUnlock()
+	if cs, ok := codes.owners[o.code]; ok {
+		return cs
+	}
+	if _, ok := codes.names[o.code]; ok {
+		return DefaultCodeSpace
+	}
+	return nil
+}
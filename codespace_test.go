@@ -0,0 +1,75 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// codeSpaceRuns numbers the runs of TestCodeSpace, e.g. with -count=2, since
+// code spaces cannot be removed once created.
+var codeSpaceRuns atomic.Int32
+
+func TestCodeSpace(t *testing.T) {
+	run := codeSpaceRuns.Add(1)
+	nameA, nameB := fmt.Sprintf("test%d/a", run), fmt.Sprintf("test%d/b", run)
+	RegisterCode(firstAllocatedCode, "ERR_REGISTERED")
+	a, b := NewCodeSpace(nameA), NewCodeSpace(nameB)
+	ca, cb := a.Code("ERR_QUOTA"), b.Code("ERR_QUOTA")
+	if ca == cb || ca == firstAllocatedCode || cb == firstAllocatedCode {
+		t.Errorf(`CodeSpace.Code("ERR_QUOTA") allocated %#x and %#x, want distinct codes not yet registered`, ca, cb)
+	}
+	if c := a.Code("ERR_QUOTA"); c != ca {
+		t.Errorf(`second a.Code("ERR_QUOTA") = %#x, want %#x`, c, ca)
+	}
+	for _, tc := range []struct {
+		space string
+		code  int
+		name  string
+		ok    bool
+	}{
+		{nameA, ca, "ERR_QUOTA", true},
+		{nameB, ca, "", false},
+		{nameB, cb, "ERR_QUOTA", true},
+		{"calmly", ca, "", false},
+		{"calmly", ERR_TRY_PANIC, "ERR_TRY_PANIC", true},
+		{"calmly", firstAllocatedCode, "ERR_REGISTERED", true},
+		{nameA, ERR_TRY_PANIC, "", false},
+		{"test/none", ca, "", false},
+	} {
+		if name, ok := LookupCode(tc.space, tc.code); name != tc.name || ok != tc.ok {
+			t.Errorf(`LookupCode(%q, %#x) = (%q, %v), want (%q, %v)`, tc.space, tc.code, name, ok, tc.name, tc.ok)
+		}
+	}
+	out := (&Outcome{level: ERROR}).SetCode(cb)
+	if ocs, ocn := out.CodeSpace(), out.CodeName(); ocs != b || ocn != "ERR_QUOTA" {
+		t.Errorf(`SetCode(b.Code("ERR_QUOTA")) has code space %v and name %q, want %v and %q`, ocs, ocn, b, "ERR_QUOTA")
+	}
+	if ocs := Try(func() { panic("test") }).CodeSpace(); ocs != DefaultCodeSpace {
+		t.Errorf(`Try(panicFunc).CodeSpace() = %v, want DefaultCodeSpace`, ocs)
+	}
+	if ocs := out.SetCode(0x0222).CodeSpace(); ocs != nil {
+		t.Errorf(`SetCode(0x0222).CodeSpace() = %v, want %v`, ocs, nil)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf(`NewCodeSpace(%q) should panic the second time`, nameA)
+		}
+	}()
+	NewCodeSpace(nameA)
+}
@@ -0,0 +1,48 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"context"
+)
+
+// handlerKey is the context key for the Outcome handler stored by NewContext.
+type handlerKey struct{}
+
+// NewContext returns a copy of ctx carrying the provided Outcome handler, for
+// request-scoped handling policies, e.g. set up by HTTP middleware.
+func NewContext(ctx context.Context, handler func(*Outcome)) context.Context {
+	return context.WithValue(ctx, handlerKey{}, handler)
+}
+
+// FromContext returns the Outcome handler carried by ctx, if any.
+func FromContext(ctx context.Context) (func(*Outcome), bool) {
+	h, ok := ctx.Value(handlerKey{}).(func(*Outcome))
+	return h, ok && h != nil
+}
+
+// HandleCtx passes the provided Outcome, if not at OK level, to the handler
+// carried by ctx. If ctx carries no handler, the Outcome is handled by Handle,
+// i.e. logged to the default Logger. It returns the Outcome, for chaining.
+func HandleCtx(ctx context.Context, o *Outcome) *Outcome {
+	h, ok := FromContext(ctx)
+	if !ok {
+		return Handle(o)
+	}
+	if o.level != OK {
+		h(o)
+	}
+	return o
+}
@@ -0,0 +1,55 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestContextHandler(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := FromContext(ctx); ok {
+		t.Errorf(`FromContext(context.Background()) reported a handler`)
+	}
+	var handled []*Outcome
+	hctx := NewContext(ctx, func(o *Outcome) {
+		handled = append(handled, o)
+	})
+	if _, ok := FromContext(hctx); !ok {
+		t.Errorf(`FromContext(NewContext(ctx, h)) reported no handler`)
+	}
+	if _, ok := FromContext(NewContext(ctx, nil)); ok {
+		t.Errorf(`FromContext(NewContext(ctx, nil)) reported a handler`)
+	}
+
+	out := Try(func() { panic("test") })
+	if ret := HandleCtx(hctx, out); ret != out {
+		t.Errorf(`HandleCtx(ctx, out) should return its argument`)
+	}
+	HandleCtx(hctx, Try(func() {}))
+	if len(handled) != 1 || handled[0] != out {
+		t.Errorf(`HandleCtx(ctx, ...) passed %v to the handler, want only the PANIC Outcome`, handled)
+	}
+
+	defer SetDefaultLogger(nil)
+	rl := NewRecordingLogger()
+	SetDefaultLogger(rl)
+	HandleCtx(ctx, &Outcome{level: ERROR, text: "abc"})
+	if got, exp := rl.Records(), []LogRecord{{ERROR, "abc"}}; !reflect.DeepEqual(got, exp) {
+		t.Errorf(`HandleCtx(context.Background(), out) logged %v, want %v`, got, exp)
+	}
+}
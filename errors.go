@@ -0,0 +1,177 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// chainError is the error returned by ChainError.
+type chainError struct {
+	outcomes []*Outcome
+	root     error
+}
+
+// Error lists each Outcome in the chain on its own line, with its level, and its
+// text and code as formatted by ErrorFormat, followed by the root error, if any.
+func (ce *chainError) Error() string {
+	format := ErrorFormat
+	if format == nil {
+		format = formatError
+	}
+	lines := make([]string, 0, len(ce.outcomes)+1)
+	for i, o := range ce.outcomes {
+		entry := *o
+		if i+1 < len(ce.outcomes) {
+			// the text of an Outcome wrapping the next one usually includes its
+			// Error, whose code is already listed on the next line
+			next := ce.outcomes[i+1]
+			if ne := next.Error(); ne != "" && ne != next.text {
+				entry.text = strings.Replace(entry.text, ne, next.text, 1)
+			}
+		}
+		line := LevelName(o.level)
+		if s := strings.TrimSpace(format(&entry)); s != "" {
+			if entry.text != "" {
+				line += ": " + s
+			} else {
+				line += " " + s
+			}
+		}
+		lines = append(lines, line)
+	}
+	if ce.root != nil {
+		lines = append(lines, ce.root.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap exposes every Outcome in the chain, as well as the root error,
+// to errors.Is and errors.As.
+func (ce *chainError) Unwrap() []error {
+	errs := make([]error, 0, len(ce.outcomes)+1)
+	for _, o := range ce.outcomes {
+		errs = append(errs, o)
+	}
+	if ce.root != nil {
+		errs = append(errs, ce.root)
+	}
+	return errs
+}
+
+// ChainError returns an error describing the whole chain of Outcomes starting
+// with the receiver: each Outcome whose Err is (or wraps, as reported by
+// errors.As) another Outcome is followed by that one, and so on, down to the
+// first error that is not an Outcome and does not wrap any.
+// It returns nil if the receiver is at OK level and holds no error.
+func (o *Outcome) ChainError() error {
+	if o.level == OK && o.err == nil {
+		return nil
+	}
+	ce := &chainError{}
+	for o != nil {
+		for _, seen := range ce.outcomes {
+			if seen == o {
+				return ce
+			}
+		}
+		ce.outcomes = append(ce.outcomes, o)
+		var next *Outcome
+		if !errors.As(o.err, &next) {
+			ce.root = o.err
+		}
+		o = next
+	}
+	return ce
+}
+
+// stackError is the error returned by ErrorWithStack.
+type stackError struct {
+	o *Outcome
+}
+
+// Error returns the detailed representation of the Outcome, as formatted by %+v.
+func (se stackError) Error() string {
+	return fmt.Sprintf("%+v", se.o)
+}
+
+// Unwrap exposes the Outcome to errors.Is and errors.As.
+func (se stackError) Unwrap() error {
+	return se.o
+}
+
+// ErrorWithStack returns an error whose message includes, besides the text of
+// the receiver, its fields and info, such as a captured stack trace, like when
+// formatting the Outcome with %+v. This preserves the details for handlers that
+// only use the Error method. It returns nil if the receiver is at OK level.
+func (o *Outcome) ErrorWithStack() error {
+	if o.level == OK {
+		return nil
+	}
+	return stackError{o}
+}
+
+// Unwrap returns the error returned by the Try-ed function, if any; otherwise,
+// if the panic recovered by Try was caused by an error value, it returns that.
+// This allows errors.Is and errors.As to inspect the errors behind an Outcome.
+func (o *Outcome) Unwrap() error {
+	if o.err != nil {
+		return o.err
+	}
+	if err, ok := o.panicVal.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Causes returns the chain of errors starting with the value recovered from the
+// panic stored by the receiver, followed by the errors it wraps, as reported by
+// errors.Unwrap, down to the root cause. It returns an empty slice if no panic
+// was recovered, or the panic value is not an error.
+func (o *Outcome) Causes() []error {
+	causes := []error{}
+	err, _ := o.panicVal.(error)
+	for err != nil {
+		causes = append(causes, err)
+		err = errors.Unwrap(err)
+	}
+	return causes
+}
+
+// Wrap returns an Outcome holding the provided error, at ERROR level and with
+// the error message as text, so that the error can be handled like a recovered
+// panic. If err is nil, it returns an OK Outcome.
+func Wrap(err error) *Outcome {
+	return WrapLevel(ERROR, err)
+}
+
+// WrapLevel works like Wrap, except that the Outcome is set at the provided level,
+// unless it is not a known level, in which case ERROR is used.
+func WrapLevel(level int8, err error) *Outcome {
+	if err == nil {
+		return (&Outcome{level: OK}).stamp(time.Now())
+	}
+	return (&Outcome{err: err, level: ERROR, text: err.Error()}).stamp(time.Now()).SetLevel(level)
+}
+
+// OKOutcome returns an Outcome at OK level holding the provided value, as if
+// returned by a Try-ed function, for code returning Outcomes uniformly from both
+// its success and failure paths; see also Wrap.
+func OKOutcome(val interface{}) *Outcome {
+	return (&Outcome{level: OK, val: val}).stamp(time.Now())
+}
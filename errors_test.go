@@ -0,0 +1,173 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChainError(t *testing.T) {
+	if ce := (&Outcome{}).ChainError(); ce != nil {
+		t.Errorf(`default.ChainError() = %v, want %v`, ce, nil)
+	}
+
+	inner := &Outcome{level: PANIC, code: ERR_TRY_PANIC, text: "panic: boom", err: io.EOF}
+	outer := Try(func() error {
+		return inner
+	}).SetLevel(ERROR).SetCode(17).SetText("step 2 failed")
+	ce := outer.ChainError()
+	if ce == nil {
+		t.Fatalf(`outer.ChainError() = %v, want non-nil`, ce)
+	}
+	exp := "ERROR: step 2 failed (code: 0x0011)\nPANIC: panic: boom (code: 0x0001)\nEOF"
+	if ces := ce.Error(); ces != exp {
+		t.Errorf(`outer.ChainError().Error() = %q, want %q`, ces, exp)
+	}
+	if !errors.Is(ce, io.EOF) {
+		t.Errorf(`errors.Is(outer.ChainError(), io.EOF) = false, want true`)
+	}
+	if !errors.Is(ce, inner) {
+		t.Errorf(`errors.Is(outer.ChainError(), inner) = false, want true`)
+	}
+	var o *Outcome
+	if !errors.As(ce, &o) || o != outer {
+		t.Errorf(`errors.As(outer.ChainError(), &o) should set o to outer (got %v)`, o)
+	}
+
+	wrapped := Wrap(fmt.Errorf("step 3: %w", outer)).SetCode(18)
+	exp = "ERROR: step 3: step 2 failed (code: 0x0012)\n" + exp
+	if ces := wrapped.ChainError().Error(); ces != exp {
+		t.Errorf(`Wrap(fmt.Errorf("...: %%w", outer)).ChainError().Error() = %q, want %q`, ces, exp)
+	}
+
+	defer func(ef func(*Outcome) string) { ErrorFormat = ef }(ErrorFormat)
+	ErrorFormat = func(o *Outcome) string {
+		return fmt.Sprintf("%s [#%d]", o.text, o.code)
+	}
+	wrapped = Wrap(fmt.Errorf("step 3: %w", outer)).SetCode(18)
+	exp = "ERROR: step 3: step 2 failed [#18]\nERROR: step 2 failed [#17]\nPANIC: panic: boom [#1]\nEOF"
+	if ces := wrapped.ChainError().Error(); ces != exp {
+		t.Errorf(`Wrap(fmt.Errorf("...: %%w", outer)).ChainError().Error() with custom ErrorFormat = %q, want %q`, ces, exp)
+	}
+	ErrorFormat = formatError
+
+	inner.err = outer
+	if ces := inner.ChainError().Error(); ces != "PANIC: panic: boom (code: 0x0001)\nERROR: step 2 failed (code: 0x0011)" {
+		t.Errorf(`cyclic ChainError().Error() = %q`, ces)
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	out := Try(func() error {
+		return fmt.Errorf("wrapped: %w", io.EOF)
+	})
+	if !errors.Is(out, io.EOF) {
+		t.Errorf(`errors.Is(Try(errFunc), io.EOF) = false, want true`)
+	}
+	out = Try(func() {
+		panic(testPanicError{"test"})
+	})
+	var tpe testPanicError
+	if !errors.As(out, &tpe) || tpe.reason != "test" {
+		t.Errorf(`errors.As(Try(panicErrFunc), &tpe) should set tpe to the panic value (got %#v)`, tpe)
+	}
+	out = Try(func() {
+		panic("test")
+	})
+	if ue := out.Unwrap(); ue != nil {
+		t.Errorf(`Try(panicFunc).Unwrap() = %v, want %v`, ue, nil)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if out := Wrap(nil); out.Level() != OK || out.Err() != nil {
+		t.Errorf(`Wrap(nil) = (%q, %v), want (%q, %v)`, LevelName(out.Level()), out.Err(), LevelName(OK), nil)
+	}
+	out := Wrap(io.EOF)
+	if ol, oe, ot := out.Level(), out.Err(), out.Text(); ol != ERROR || oe != io.EOF || ot != "EOF" {
+		t.Errorf(`Wrap(io.EOF) = (%q, %v, %q), want (%q, %v, %q)`, LevelName(ol), oe, ot, LevelName(ERROR), io.EOF, "EOF")
+	}
+	if !errors.Is(out, io.EOF) {
+		t.Errorf(`errors.Is(Wrap(io.EOF), io.EOF) = false, want true`)
+	}
+	if ol := WrapLevel(FATAL, io.EOF).Level(); ol != FATAL {
+		t.Errorf(`WrapLevel(FATAL, io.EOF).Level() = %q, want %q`, LevelName(ol), LevelName(FATAL))
+	}
+	if ol := WrapLevel(17, io.EOF).Level(); ol != ERROR {
+		t.Errorf(`WrapLevel(17, io.EOF).Level() = %q, want %q`, LevelName(ol), LevelName(ERROR))
+	}
+	log := &mockLogger{}
+	Wrap(io.EOF).Log(log)
+	if log.log != "EOF\n" {
+		t.Errorf(`logging test got %q, want %q`, log.log, "EOF\n")
+	}
+}
+
+func TestErrorWithStack(t *testing.T) {
+	if err := Try(func() {}).ErrorWithStack(); err != nil {
+		t.Errorf(`Try(goodFunc).ErrorWithStack() = %v, want %v`, err, nil)
+	}
+	out := Try(func() {
+		panic(io.EOF)
+	})
+	err := out.ErrorWithStack()
+	if es := err.Error(); !strings.HasPrefix(es, out.Error()+"\ngoroutine ") || !strings.Contains(es, "calmly.TestErrorWithStack") {
+		t.Errorf(`Try(panicFunc).ErrorWithStack().Error() does not contain the text and stack trace (got %q)`, es)
+	}
+	var o *Outcome
+	if !errors.As(err, &o) || o != out {
+		t.Errorf(`errors.As(Try(panicFunc).ErrorWithStack(), &o) should set o to the Outcome (got %v)`, o)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Errorf(`errors.Is(Try(panicFunc).ErrorWithStack(), io.EOF) = false, want true`)
+	}
+	if es := fmt.Sprint(out); es != out.Error() {
+		t.Errorf(`fmt.Sprint(Try(panicFunc)) = %q, want the short %q`, es, out.Error())
+	}
+}
+
+func TestCauses(t *testing.T) {
+	inner := fmt.Errorf("inner: %w", io.EOF)
+	outer := fmt.Errorf("outer: %w", inner)
+	out := Try(func() {
+		panic(outer)
+	})
+	if got, exp := out.Causes(), []error{outer, inner, io.EOF}; !reflect.DeepEqual(got, exp) {
+		t.Errorf(`Try(panicWrappedFunc).Causes() = %v, want %v`, got, exp)
+	}
+	for name, f := range map[string]func(){
+		"goodFunc":  func() {},
+		"panicFunc": func() { panic("test") },
+	} {
+		if got := Try(f).Causes(); got == nil || len(got) != 0 {
+			t.Errorf(`Try(%s).Causes() = %#v, want an empty slice`, name, got)
+		}
+	}
+}
+
+func TestOKOutcome(t *testing.T) {
+	out := OKOutcome(17)
+	if ol, ov, oe := out.Level(), out.Value(), out.Err(); ol != OK || ov != 17 || oe != nil {
+		t.Errorf(`OKOutcome(17) = (%q, %v, %v), want (%q, %v, %v)`, LevelName(ol), ov, oe, LevelName(OK), 17, nil)
+	}
+	if ov, oe := out.Result(); ov != 17 || oe != nil {
+		t.Errorf(`OKOutcome(17).Result() = (%v, %v), want (%v, %v)`, ov, oe, 17, nil)
+	}
+}
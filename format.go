@@ -0,0 +1,98 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"os"
+	"strings"
+)
+
+// ANSI escape sequences used by FormatInfo.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[1;33m"
+)
+
+// FormatInfo renders the info of the receiver for display, one entry after the
+// other. If colored is true and stderr is a terminal, captured stack traces are
+// highlighted with ANSI escape sequences: the frames belonging to the Go runtime
+// or to this package are dimmed, while the panic and the first frame of the code
+// that caused it stand out. Otherwise, the info is returned as plain text.
+func (o *Outcome) FormatInfo(colored bool) string {
+	return o.formatInfo(colored && isTerminal(os.Stderr))
+}
+
+// isTerminal reports whether f is a character device, such as a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// formatInfo renders the info of the receiver, with or without colors.
+func (o *Outcome) formatInfo(colored bool) string {
+	if !colored {
+		return strings.Join(o.info, "\n")
+	}
+	entries := append([]string(nil), o.info...)
+	for _, i := range o.stacks {
+		entries[i] = colorStack(entries[i])
+	}
+	return strings.Join(entries, "\n")
+}
+
+// colorStack highlights the formatted stack trace st (see FormatInfo).
+func colorStack(st string) string {
+	lines := strings.Split(st, "\n")
+	var b strings.Builder
+	color, userFound := "", false
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		switch {
+		case line == "":
+			b.WriteString(line)
+			continue
+		case strings.HasPrefix(line, "goroutine "):
+			// the header of each goroutine
+			color = ansiBold
+		case strings.HasPrefix(line, "\t"):
+			// the location of the function on the previous line: same color
+		case strings.HasPrefix(line, "panic("):
+			color = ansiRed
+		default:
+			name := strings.TrimPrefix(line, "created by ")
+			if p := strings.LastIndexByte(name, '('); p > 0 {
+				name = name[:p]
+			}
+			if isInternalFrame(name) {
+				color = ansiDim
+			} else if !userFound {
+				color, userFound = ansiYellow, true
+			} else {
+				color = ""
+			}
+		}
+		if color == "" {
+			b.WriteString(line)
+		} else {
+			b.WriteString(color + line + ansiReset)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"testing"
+)
+
+func TestFormatInfo(t *testing.T) {
+	st := "goroutine 7 [running]:\n" +
+		"panic({0x4c2f20?, 0x5e8a30?})\n\t/go/src/runtime/panic.go:770 +0x132\n" +
+		"main.(*worker).run(0xc000010000)\n\t/app/worker.go:42 +0x25\n" +
+		"github.com/agext/calmly.Try({0x4b5e40, 0xc000012018}, {0x0, 0x0, 0x0})\n\t/go/src/github.com/agext/calmly/calmly.go:105 +0x2eb\n" +
+		"main.main()\n\t/app/main.go:12 +0x3e\n"
+	// only the entries recorded as stack traces are colored
+	out := &Outcome{level: PANIC, info: []string{"state: idle", st, "goroutine 8 is idle"}, stacks: []int{1}}
+	plain := "state: idle\ngoroutine 7 [running]:\n" +
+		"panic({0x4c2f20?, 0x5e8a30?})\n\t/go/src/runtime/panic.go:770 +0x132\n" +
+		"main.(*worker).run(0xc000010000)\n\t/app/worker.go:42 +0x25\n" +
+		"github.com/agext/calmly.Try({0x4b5e40, 0xc000012018}, {0x0, 0x0, 0x0})\n\t/go/src/github.com/agext/calmly/calmly.go:105 +0x2eb\n" +
+		"main.main()\n\t/app/main.go:12 +0x3e\n\ngoroutine 8 is idle"
+	if got := out.formatInfo(false); got != plain {
+		t.Errorf(`formatInfo(false) = %q, want %q`, got, plain)
+	}
+	colored := "state: idle\n\x1b[1mgoroutine 7 [running]:\x1b[0m\n" +
+		"\x1b[31mpanic({0x4c2f20?, 0x5e8a30?})\x1b[0m\n\x1b[31m\t/go/src/runtime/panic.go:770 +0x132\x1b[0m\n" +
+		"\x1b[1;33mmain.(*worker).run(0xc000010000)\x1b[0m\n\x1b[1;33m\t/app/worker.go:42 +0x25\x1b[0m\n" +
+		"\x1b[2mgithub.com/agext/calmly.Try({0x4b5e40, 0xc000012018}, {0x0, 0x0, 0x0})\x1b[0m\n\x1b[2m\t/go/src/github.com/agext/calmly/calmly.go:105 +0x2eb\x1b[0m\n" +
+		"main.main()\n\t/app/main.go:12 +0x3e\n\ngoroutine 8 is idle"
+	if got := out.formatInfo(true); got != colored {
+		t.Errorf(`formatInfo(true) = %q, want %q`, got, colored)
+	}
+	if got := out.FormatInfo(false); got != plain {
+		t.Errorf(`FormatInfo(false) = %q, want %q`, got, plain)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Frame describes a single call stack frame.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// pkgPrefix is the prefix shared by the names of all functions in this package.
+var pkgPrefix = reflect.TypeOf(Outcome{}).PkgPath() + "."
+
+// callers returns the program counters of the calling goroutine's stack,
+// skipping the given number of frames above the caller of callers.
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 32)
+	for {
+		n := runtime.Callers(skip+2, pcs)
+		if n < len(pcs) {
+			return pcs[:n]
+		}
+		pcs = make([]uintptr, 2*len(pcs))
+	}
+}
+
+// isInternalFrame reports whether the named function belongs to the Go runtime
+// or to this package.
+func isInternalFrame(function string) bool {
+	return strings.HasPrefix(function, "runtime.") || strings.HasPrefix(function, pkgPrefix)
+}
+
+// goexiting reports whether the calling deferred function is being run because
+// the goroutine is terminating via runtime.Goexit, rather than due to a panic.
+func goexiting() bool {
+	frames := runtime.CallersFrames(callers(1))
+	for {
+		f, more := frames.Next()
+		switch f.Function {
+		case "runtime.Goexit":
+			return true
+		case "runtime.gopanic":
+			return false
+		}
+		if !more {
+			return false
+		}
+	}
+}
+
+// Callers returns the program counters of the goroutine in which the panic stored
+// by the receiver was recovered, as captured by runtime.Callers, or nil if no panic
+// was recovered. They can be symbolized with runtime.CallersFrames.
+func (o *Outcome) Callers() []uintptr {
+	return o.pcs
+}
+
+// OriginFrame returns the frame where the panic stored by the receiver
+// originated, i.e. the first frame that belongs neither to the Go runtime nor to
+// this package. The second return value is false if no panic was recovered, or
+// no such frame exists.
+func (o *Outcome) OriginFrame() (Frame, bool) {
+	if len(o.pcs) == 0 {
+		return Frame{}, false
+	}
+	frames := runtime.CallersFrames(o.pcs)
+	for {
+		f, more := frames.Next()
+		if f.Function != "" && !isInternalFrame(f.Function) {
+			return Frame{Function: f.Function, File: f.File, Line: f.Line}, true
+		}
+		if !more {
+			return Frame{}, false
+		}
+	}
+}
+
+// Frames returns the stack frames of the goroutine in which the panic stored by
+// the receiver was recovered, or nil if no panic was recovered. The frames are
+// resolved from the program counters captured at the time of recovery, starting
+// with those of the Go runtime handling the panic; callers interested only in
+// their own code can drop them, e.g. by skipping up to OriginFrame.
+func (o *Outcome) Frames() []Frame {
+	if len(o.pcs) == 0 {
+		return nil
+	}
+	fs := make([]Frame, 0, len(o.pcs))
+	frames := runtime.CallersFrames(o.pcs)
+	for {
+		f, more := frames.Next()
+		fs = append(fs, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		if !more {
+			return fs
+		}
+	}
+}
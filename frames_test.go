@@ -0,0 +1,114 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// These tests live in an external package, so that the test functions are
+// seen as user code rather than as frames belonging to calmly.
+package calmly_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/agext/calmly"
+)
+
+func TestOriginFrame(t *testing.T) {
+	if f, ok := calmly.Try(func() {}).OriginFrame(); ok {
+		t.Errorf(`Try(goodFunc).OriginFrame() = %v, true, want false`, f)
+	}
+
+	var file string
+	var line int
+	out := calmly.Try(func() {
+		_, file, line, _ = runtime.Caller(0)
+		panic("boom")
+	})
+	f, ok := out.OriginFrame()
+	if !ok {
+		t.Fatalf(`Try(panicFunc).OriginFrame() reported no frame`)
+	}
+	if f.File != file || f.Line != line+1 {
+		t.Errorf(`Try(panicFunc).OriginFrame() = %s:%d, want %s:%d`, f.File, f.Line, file, line+1)
+	}
+	if !strings.Contains(f.Function, "calmly_test.TestOriginFrame") {
+		t.Errorf(`Try(panicFunc).OriginFrame().Function = %q, want the test closure`, f.Function)
+	}
+
+	out = calmly.Try(func() {
+		a, b := 1, 0
+		_, file, line, _ = runtime.Caller(0)
+		a = a / b
+		_ = a
+	})
+	f, ok = out.OriginFrame()
+	if !ok {
+		t.Fatalf(`Try(divByZero).OriginFrame() reported no frame`)
+	}
+	if f.File != file || f.Line != line+1 {
+		t.Errorf(`Try(divByZero).OriginFrame() = %s:%d, want %s:%d`, f.File, f.Line, file, line+1)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	if fs := calmly.Try(func() {}).Frames(); fs != nil {
+		t.Errorf(`Try(goodFunc).Frames() = %v, want %v`, fs, nil)
+	}
+
+	out := calmly.Try(func() {
+		panic("boom")
+	})
+	fs := out.Frames()
+	if len(fs) == 0 {
+		t.Fatalf(`Try(panicFunc).Frames() is empty`)
+	}
+	if fs[0].Function != "runtime.gopanic" {
+		t.Errorf(`Try(panicFunc).Frames()[0].Function = %q, want %q`, fs[0].Function, "runtime.gopanic")
+	}
+	origin, _ := out.OriginFrame()
+	var found, tryFound, testFound bool
+	for _, f := range fs {
+		switch {
+		case f == origin:
+			found = true
+		case f.Function == "github.com/agext/calmly.Try":
+			tryFound = found
+		case f.Function == "github.com/agext/calmly_test.TestFrames":
+			testFound = tryFound
+		}
+	}
+	if !found || !tryFound || !testFound {
+		t.Errorf(`Try(panicFunc).Frames() does not list the origin, Try and the test function in order (got %v)`, fs)
+	}
+}
+
+func TestCallers(t *testing.T) {
+	if pcs := calmly.Try(func() {}).Callers(); pcs != nil {
+		t.Errorf(`Try(goodFunc).Callers() = %v, want %v`, pcs, nil)
+	}
+	out := calmly.Try(func() {
+		panic("boom")
+	})
+	pcs := out.Callers()
+	if len(pcs) == 0 {
+		t.Fatalf(`Try(panicFunc).Callers() is empty`)
+	}
+	frames := runtime.CallersFrames(pcs)
+	for _, f := range out.Frames() {
+		rf, _ := frames.Next()
+		if rf.Function != f.Function || rf.File != f.File || rf.Line != f.Line {
+			t.Errorf(`Try(panicFunc).Callers() symbolized to %s (%s:%d), want %v`, rf.Function, rf.File, rf.Line, f)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"fmt"
+)
+
+// TryValue calls f like `Try` does, returning the value produced by f with its
+// concrete type, rather than storing it in the Outcome. The error returned by f
+// is stored in the Outcome as usual. If f panics, the zero value of T is returned.
+func TryValue[T any](f func() (T, error), opts ...Option) (o *Outcome, v T) {
+	o = Try(func() (err error) {
+		v, err = f()
+		return
+	}, opts...)
+	return
+}
+
+// TryValue2 works like TryValue, for functions returning two values and an error.
+// If f panics, the zero values of A and B are returned.
+func TryValue2[A, B any](f func() (A, B, error), opts ...Option) (o *Outcome, a A, b B) {
+	o = Try(func() (err error) {
+		a, b, err = f()
+		return
+	}, opts...)
+	return
+}
+
+// MustValue panics like `Must` if o is not at OK level, or holds a non-nil error;
+// otherwise, it returns v. It is meant to be used with TryValue, as in
+// `cfg := calmly.MustValue(calmly.TryValue(loadConfig))`.
+func MustValue[T any](o *Outcome, v T) T {
+	Must(o)
+	return v
+}
+
+// Must1 returns v if err is nil, and panics with err otherwise, so that the error
+// is available via the Err method of the Outcome if the panic is recovered by Try.
+// It is meant for calls returning a value and an error, inside Try-ed functions:
+// `data := calmly.Must1(os.ReadFile(name))`.
+func Must1[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Must2 works like Must1, for calls returning two values and an error.
+func Must2[A, B any](a A, b B, err error) (A, B) {
+	if err != nil {
+		panic(err)
+	}
+	return a, b
+}
+
+// TryEach calls f for each of the items, recovering from any panic it may cause
+// like `Try` does, so that a failure for one item does not prevent processing the
+// others. It returns the Outcomes in the same order as the items. For failed
+// calls (i.e. that panicked or returned an error) the item is added to the info.
+func TryEach[T any](items []T, f func(T) error, opts ...Option) []*Outcome {
+	outcomes := make([]*Outcome, len(items))
+	opts = append(opts[:len(opts):len(opts)], withDeferredNotify())
+	for i, item := range items {
+		o := Try(func() error {
+			return f(item)
+		}, opts...)
+		if o.level != OK || o.err != nil {
+			o.AddInfo(fmt.Sprintf("item[%d]: %#v", i, item))
+		}
+		notify(o)
+		outcomes[i] = o
+	}
+	return outcomes
+}
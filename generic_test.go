@@ -0,0 +1,144 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestTryValue(t *testing.T) {
+	out, v := TryValue(func() (int, error) {
+		return 17, fmt.Errorf("test")
+	})
+	if ol := out.Level(); ol != OK {
+		t.Errorf(`TryValue(goodFunc).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(OK))
+	}
+	if v != 17 {
+		t.Errorf(`TryValue(goodFunc) value = %d, want %d`, v, 17)
+	}
+	if oe := out.Err(); oe == nil || oe.Error() != "test" {
+		t.Errorf(`TryValue(goodFunc).Err() = %v, want %q`, oe, "test")
+	}
+	if ov := out.Value(); ov != nil {
+		t.Errorf(`TryValue(goodFunc).Value() = %v, want %v`, ov, nil)
+	}
+
+	out, s := TryValue(func() (string, error) {
+		panic("test")
+	})
+	if ol := out.Level(); ol != PANIC {
+		t.Errorf(`TryValue(panicFunc).Level() = %q (%d), want %q`, LevelName(ol), ol, LevelName(PANIC))
+	}
+	if s != "" {
+		t.Errorf(`TryValue(panicFunc) value = %q, want %q`, s, "")
+	}
+}
+
+func TestMustValue(t *testing.T) {
+	if v := MustValue(TryValue(func() (int, error) { return 17, nil })); v != 17 {
+		t.Errorf(`MustValue(TryValue(goodFunc)) = %d, want %d`, v, 17)
+	}
+	out := Try(func() {
+		MustValue(TryValue(func() (int, error) { return 17, fmt.Errorf("test") }))
+	})
+	if ol, ot := out.Level(), out.Text(); ol != PANIC || ot != "panic: test" {
+		t.Errorf(`MustValue(TryValue(errFunc)) recovered as (%q, %q), want (%q, %q)`, LevelName(ol), ot, LevelName(PANIC), "panic: test")
+	}
+}
+
+func TestTryEach(t *testing.T) {
+	var mu sync.Mutex
+	hooked := map[*Outcome][][]string{}
+	defer setOnOutcome(func(o *Outcome) {
+		mu.Lock()
+		hooked[o] = append(hooked[o], append([]string(nil), o.Info()...))
+		mu.Unlock()
+	})()
+	outs := TryEach([]int{2, 0, 1, -1}, func(n int) error {
+		if n < 0 {
+			return fmt.Errorf("negative")
+		}
+		_ = 2 / n
+		return nil
+	})
+	if len(outs) != 4 {
+		t.Fatalf(`len(TryEach(items, f)) = %d, want %d`, len(outs), 4)
+	}
+	for i, exp := range []struct {
+		level int8
+		err   bool
+		info  string
+	}{
+		{OK, false, ""},
+		{PANIC, true, "item[1]: 0"},
+		{OK, false, ""},
+		{OK, true, "item[3]: -1"},
+	} {
+		out := outs[i]
+		if ol := out.Level(); ol != exp.level || (out.Err() != nil) != exp.err {
+			t.Errorf(`TryEach(items, f)[%d] = (%q, %v), want level %q`, i, LevelName(ol), out.Err(), LevelName(exp.level))
+		}
+		info := out.Info()
+		if exp.info == "" {
+			if len(info) != 0 {
+				t.Errorf(`TryEach(items, f)[%d].Info() = %q, want none`, i, info)
+			}
+		} else if len(info) == 0 || info[len(info)-1] != exp.info {
+			t.Errorf(`TryEach(items, f)[%d].Info() = %q, want it to end with %q`, i, info, exp.info)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if h := hooked[outs[1]]; len(h) != 1 || len(h[0]) == 0 || h[0][len(h[0])-1] != "item[1]: 0" {
+		t.Errorf(`TryEach(items, f)[1] passed to OnOutcome with info %q, want it once, ending with %q`, h, "item[1]: 0")
+	}
+}
+
+func TestTryValue2(t *testing.T) {
+	out, a, b := TryValue2(func() (int, string, error) {
+		return 17, "abc", nil
+	})
+	if ol := out.Level(); ol != OK || a != 17 || b != "abc" {
+		t.Errorf(`TryValue2(goodFunc) = (%q, %d, %q), want (%q, %d, %q)`, LevelName(ol), a, b, LevelName(OK), 17, "abc")
+	}
+
+	out, a, b = TryValue2(func() (int, string, error) {
+		panic("test")
+	})
+	if ol := out.Level(); ol != PANIC || a != 0 || b != "" {
+		t.Errorf(`TryValue2(panicFunc) = (%q, %d, %q), want (%q, %d, %q)`, LevelName(ol), a, b, LevelName(PANIC), 0, "")
+	}
+}
+
+func TestMust1(t *testing.T) {
+	if v := Must1(17, nil); v != 17 {
+		t.Errorf(`Must1(17, nil) = %v, want %v`, v, 17)
+	}
+	if a, b := Must2(17, "abc", nil); a != 17 || b != "abc" {
+		t.Errorf(`Must2(17, "abc", nil) = (%v, %v), want (%v, %v)`, a, b, 17, "abc")
+	}
+	for name, f := range map[string]func(){
+		"Must1": func() { Must1(17, io.EOF) },
+		"Must2": func() { Must2(17, "abc", io.EOF) },
+	} {
+		out := Try(f)
+		if ol, oe := out.Level(), out.Err(); ol != PANIC || oe != io.EOF {
+			t.Errorf(`Try(%s(..., io.EOF)) = (%q, %v), want (%q, %v)`, name, LevelName(ol), oe, LevelName(PANIC), io.EOF)
+		}
+	}
+}
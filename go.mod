@@ -0,0 +1,3 @@
+module github.com/agext/calmly
+
+go 1.21
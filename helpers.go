@@ -14,11 +14,18 @@
 
 package calmly
 
+import (
+	"fmt"
+	"sync"
+)
+
 // Outcome levels match logging levels in agext/log
 const (
 	OK int8 = 0
 
-	ERROR int8 = iota + 3
+	INFO int8 = iota + 1
+	WARN
+	ERROR
 	PANIC
 	FATAL
 )
@@ -27,25 +34,127 @@ const (
 const (
 	ERR_TRY_ARG int = iota
 	ERR_TRY_PANIC
+	ERR_TRY_CONTEXT
+	ERR_MERGED
+	ERR_TRY_TIMEOUT
+	ERR_TRY_GOEXIT
 )
 
-func levelName(l int8) string {
-	switch l {
-	case OK:
-		return "OK"
-	case ERROR:
-		return "ERROR"
-	case PANIC:
-		return "PANIC"
-	case FATAL:
-		return "FATAL"
+// codes holds the names of all known error codes, including those added via
+// RegisterCode and allocated by CodeSpaces, as well as the HTTP statuses added
+// via RegisterCodeStatus.
+var codes = struct {
+	sync.RWMutex
+	names    map[int]string
+	statuses map[int]int
+	owners   map[int]*CodeSpace    // the space of each allocated code
+	spaces   map[string]*CodeSpace // the spaces, by name
+	next     int                   // the next code to try allocating
+}{
+	names: map[int]string{
+		ERR_TRY_ARG:     "ERR_TRY_ARG",
+		ERR_TRY_PANIC:   "ERR_TRY_PANIC",
+		ERR_TRY_CONTEXT: "ERR_TRY_CONTEXT",
+		ERR_MERGED:      "ERR_MERGED",
+		ERR_TRY_TIMEOUT: "ERR_TRY_TIMEOUT",
+		ERR_TRY_GOEXIT:  "ERR_TRY_GOEXIT",
+	},
+	statuses: map[int]int{},
+	owners:   map[int]*CodeSpace{},
+	spaces:   map[string]*CodeSpace{"calmly": DefaultCodeSpace},
+	next:     firstAllocatedCode,
+}
+
+// RegisterCode associates a name with an error code, making it available via
+// the CodeName method of Outcome. It panics if the code is already registered
+// with a different name, including the predefined ones.
+//
+// RegisterCode is safe for concurrent use, but codes are meant to be
+// registered during program initialization.
+func RegisterCode(code int, name string) {
+	codes.Lock()
+	defer codes.Unlock()
+	if prev, ok := codes.names[code]; ok && prev != name {
+		panic(fmt.Sprintf("calmly: code 0x%04x already registered as %s", code, prev))
+	}
+	codes.names[code] = name
+}
+
+func codeName(code int) (string, bool) {
+	codes.RLock()
+	name, ok := codes.names[code]
+	codes.RUnlock()
+	return name, ok
+}
+
+// levels holds the names of all known levels, including those added via
+// RegisterLevel.
+var levels = struct {
+	sync.RWMutex
+	names map[int8]string
+}{names: map[int8]string{
+	OK:    "OK",
+	INFO:  "INFO",
+	WARN:  "WARN",
+	ERROR: "ERROR",
+	PANIC: "PANIC",
+	FATAL: "FATAL",
+}}
+
+func isBuiltinLevel(l int8) bool {
+	return l == OK || l >= INFO && l <= FATAL
+}
+
+// RegisterLevel adds a custom level with the provided name, or renames a
+// previously registered custom level. Custom levels are accepted by SetLevel,
+// and are logged using Print() by Log. Custom levels above ERROR are considered
+// error conditions, same as the predefined ones.
+// RegisterLevel panics if l is one of the predefined levels.
+//
+// RegisterLevel is safe for concurrent use, but levels are meant to be
+// registered during program initialization, before any Outcome uses them.
+func RegisterLevel(l int8, name string) {
+	if isBuiltinLevel(l) {
+		panic(fmt.Sprintf("calmly: cannot redefine predefined level %d (%s)", l, LevelName(l)))
+	}
+	levels.Lock()
+	levels.names[l] = name
+	levels.Unlock()
+}
+
+// LevelName returns the name of the provided level, including custom levels
+// added via RegisterLevel, or "?" for unknown levels.
+func LevelName(l int8) string {
+	levels.RLock()
+	defer levels.RUnlock()
+	if name, ok := levels.names[l]; ok {
+		return name
 	}
 	return "?"
 }
 
+// SeverityLess reports whether level a is less severe than level b. Among the
+// predefined levels, OK is the lowest, followed by INFO, WARN, ERROR, PANIC and
+// FATAL, the highest; custom levels are ordered by their numeric value.
+func SeverityLess(a, b int8) bool {
+	return a < b
+}
+
 // Logger defines the interface expected by the Log method of Outcome
 type Logger interface {
 	Fatal(...interface{})
 	Panic(...interface{})
 	Print(...interface{})
 }
+
+// LeveledLogger defines the interface expected by the LogLeveled method of Outcome,
+// for loggers with a single entry point taking the level as argument.
+type LeveledLogger interface {
+	Log(level int8, v ...interface{})
+}
+
+// KVLogger defines the interface expected by the LogKV method of Outcome, for
+// structured loggers taking alternating keys and values, such as go-kit's.
+type KVLogger interface {
+	Log(keyvals ...interface{}) error
+}
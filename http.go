@@ -0,0 +1,153 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// ClientErrors controls whether Outcomes at ERROR level are mapped by HTTPStatus
+// to 400 Bad Request, for services where such errors are caused by invalid
+// requests, rather than to 500 Internal Server Error.
+// ClientErrors is meant to be set during program initialization.
+var ClientErrors = false
+
+// RegisterCodeStatus associates an HTTP status with an error code, to be returned
+// by HTTPStatus for Outcomes not at OK level storing that code, instead of the
+// status derived from their level.
+//
+// RegisterCodeStatus is safe for concurrent use, but statuses are meant to be
+// registered during program initialization.
+func RegisterCodeStatus(code, status int) {
+	codes.Lock()
+	codes.statuses[code] = status
+	codes.Unlock()
+}
+
+// HTTPStatus returns the HTTP status matching the receiver: the one registered
+// for its code via RegisterCodeStatus, if any; otherwise, 200 OK for levels
+// below ERROR, 500 Internal Server Error for PANIC, FATAL and any custom level
+// above ERROR, and for ERROR either 500 or 400 Bad Request, if ClientErrors is set.
+func (o *Outcome) HTTPStatus() int {
+	if o.level == OK {
+		return http.StatusOK
+	}
+	codes.RLock()
+	status, ok := codes.statuses[o.code]
+	codes.RUnlock()
+	switch {
+	case ok:
+		return status
+	case o.level < ERROR:
+		return http.StatusOK
+	case o.level == ERROR && ClientErrors:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// WriteHTTP writes a response matching the receiver to w, with the status given
+// by HTTPStatus, and the standard text for that status as a plain text body.
+// The details of the Outcome are deliberately left out, so as to not disclose
+// any internals to the client; they are meant to be logged instead.
+func (o *Outcome) WriteHTTP(w http.ResponseWriter) *Outcome {
+	status := o.HTTPStatus()
+	h := w.Header()
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write([]byte(http.StatusText(status) + "\n"))
+	return o
+}
+
+// responseWriter records whether a response was started, for Middleware.
+type responseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	rw.started = true
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.started = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, for streaming handlers, if the original
+// ResponseWriter supports it.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.started = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, for handlers taking over the connection,
+// e.g. for websockets, if the original ResponseWriter supports it.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	rw.started = true
+	return h.Hijack()
+}
+
+// Unwrap gives http.ResponseController access to the original ResponseWriter.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Middleware returns a function wrapping an http.Handler so that it is called
+// like `Try` does, to prevent any panic it may cause from reaching the server.
+// For non-OK Outcomes, unless the handler already started writing the response,
+// a response is written via WriteHTTP, i.e. 500 Internal Server Error for panics.
+//
+// Before that, if the request context carries an Outcome handler (see NewContext),
+// the Outcome is passed to it, allowing it to customize the response, e.g. by
+// setting a code registered via RegisterCodeStatus; otherwise, the Outcome is
+// logged to log (if not nil) via LogGraceful, so that the server keeps running.
+//
+// Panics with http.ErrAbortHandler are propagated, to abort the response as
+// intended.
+func Middleware(log Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w}
+			o := Try(func() {
+				next.ServeHTTP(rw, r)
+			})
+			if o.level == OK {
+				return
+			}
+			if o.panicVal == http.ErrAbortHandler {
+				panic(http.ErrAbortHandler)
+			}
+			if h, ok := FromContext(r.Context()); ok {
+				h(o)
+			} else if log != nil {
+				o.LogGraceful(log)
+			}
+			if !rw.started {
+				o.WriteHTTP(w)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	for _, test := range []struct {
+		level  int8
+		code   int
+		status int
+	}{
+		{OK, 0, http.StatusOK},
+		{OK, 17, http.StatusOK},
+		{WARN, 0, http.StatusOK},
+		{ERROR, 0, http.StatusInternalServerError},
+		{PANIC, ERR_TRY_PANIC, http.StatusInternalServerError},
+		{FATAL, 0, http.StatusInternalServerError},
+		{PANIC, 404, http.StatusInternalServerError},
+	} {
+		if status := (&Outcome{level: test.level, code: test.code}).HTTPStatus(); status != test.status {
+			t.Errorf(`Outcome{%s, %d}.HTTPStatus() = %d, want %d`, LevelName(test.level), test.code, status, test.status)
+		}
+	}
+
+	defer func() {
+		ClientErrors = false
+		codes.Lock()
+		delete(codes.statuses, 404)
+		codes.Unlock()
+	}()
+	RegisterCodeStatus(404, http.StatusNotFound)
+	ClientErrors = true
+	if status := (&Outcome{level: ERROR}).HTTPStatus(); status != http.StatusBadRequest {
+		t.Errorf(`Outcome{ERROR}.HTTPStatus() with ClientErrors = %d, want %d`, status, http.StatusBadRequest)
+	}
+	for _, level := range []int8{WARN, ERROR, PANIC} {
+		if status := (&Outcome{level: level, code: 404}).HTTPStatus(); status != http.StatusNotFound {
+			t.Errorf(`Outcome{%s, 404}.HTTPStatus() = %d, want %d`, LevelName(level), status, http.StatusNotFound)
+		}
+	}
+}
+
+func TestWriteHTTP(t *testing.T) {
+	out := Try(func() { panic("secret details") })
+	rec := httptest.NewRecorder()
+	if ret := out.WriteHTTP(rec); ret != out {
+		t.Errorf(`WriteHTTP(w) should return its receiver`)
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf(`Try(panicFunc).WriteHTTP(w) status = %d, want %d`, rec.Code, http.StatusInternalServerError)
+	}
+	if body, exp := rec.Body.String(), "Internal Server Error\n"; body != exp {
+		t.Errorf(`Try(panicFunc).WriteHTTP(w) body = %q, want %q`, body, exp)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf(`Try(panicFunc).WriteHTTP(w) Content-Type = %q, want %q`, ct, "text/plain; charset=utf-8")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	rl := NewRecordingLogger()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fine"))
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc("/late", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late boom")
+	})
+	mux.HandleFunc("/abort", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+	h := Middleware(rl)(mux)
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	if rec := serve(httptest.NewRequest("GET", "/ok", nil)); rec.Code != http.StatusOK || rec.Body.String() != "fine" {
+		t.Errorf(`GET /ok = (%d, %q), want (%d, %q)`, rec.Code, rec.Body.String(), http.StatusOK, "fine")
+	}
+	if rec := serve(httptest.NewRequest("GET", "/panic", nil)); rec.Code != http.StatusInternalServerError || rec.Body.String() != "Internal Server Error\n" {
+		t.Errorf(`GET /panic = (%d, %q), want (%d, %q)`, rec.Code, rec.Body.String(), http.StatusInternalServerError, "Internal Server Error\n")
+	}
+	if rec := serve(httptest.NewRequest("GET", "/late", nil)); rec.Code != http.StatusAccepted || rec.Body.String() != "" {
+		t.Errorf(`GET /late = (%d, %q), want the response started by the handler (%d)`, rec.Code, rec.Body.String(), http.StatusAccepted)
+	}
+	if msgs := rl.Messages(); len(msgs) != 2 || msgs[0] != "panic: boom (code: 0x0001)" || msgs[1] != "panic: late boom (code: 0x0001)" {
+		t.Errorf(`Middleware(log) logged %q, want both panics`, msgs)
+	}
+	if out := Try(func() { serve(httptest.NewRequest("GET", "/abort", nil)) }); out.PanicValue() != http.ErrAbortHandler {
+		t.Errorf(`GET /abort did not propagate http.ErrAbortHandler (got %v)`, out.PanicValue())
+	}
+
+	defer func() {
+		codes.Lock()
+		delete(codes.statuses, 418)
+		codes.Unlock()
+	}()
+	RegisterCodeStatus(418, http.StatusTeapot)
+	var handled *Outcome
+	r := httptest.NewRequest("GET", "/panic", nil)
+	r = r.WithContext(NewContext(r.Context(), func(o *Outcome) {
+		handled = o.SetCode(418)
+	}))
+	if rec := serve(r); rec.Code != http.StatusTeapot || handled == nil || len(rl.Messages()) != 2 {
+		t.Errorf(`GET /panic with context handler = %d, want %d, without logging`, rec.Code, http.StatusTeapot)
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (hr *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hr.hijacked = true
+	return nil, nil, nil
+}
+
+func TestMiddlewareFlushHijack(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		if _, _, err := w.(http.Hijacker).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf(`Hijack() on a ResponseWriter without support returned %v, want %v`, err, http.ErrNotSupported)
+		}
+		panic("test")
+	})).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !rec.Flushed || rec.Code != http.StatusOK {
+		t.Errorf(`Middleware(flushingHandler) = (flushed: %v, %d), want (flushed: %v, %d)`, rec.Flushed, rec.Code, true, http.StatusOK)
+	}
+
+	hr := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Hijacker).Hijack()
+		panic("test")
+	})).ServeHTTP(hr, httptest.NewRequest("GET", "/", nil))
+	if !hr.hijacked || hr.Body.Len() != 0 {
+		t.Errorf(`Middleware(hijackingHandler) = (hijacked: %v, %q), want (hijacked: %v, no response)`, hr.hijacked, hr.Body.String(), true)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// lastOutcome keeps the Outcome of the last panic recovered by a wrapper.
+type lastOutcome struct {
+	last atomic.Pointer[Outcome]
+}
+
+// LastOutcome returns the Outcome of the last panic recovered by the wrapper,
+// or nil if none was.
+func (lo *lastOutcome) LastOutcome() *Outcome {
+	return lo.last.Load()
+}
+
+// recover stores the details of a panic, if any, and turns it into the error
+// returned by the wrapped call. It must be deferred directly.
+func (lo *lastOutcome) recover(n *int, err *error) {
+	if p := recover(); p != nil {
+		o := &Outcome{}
+		o.setPanic(config{}, p)
+		notify(o)
+		lo.last.Store(o)
+		*n, *err = 0, o
+	}
+}
+
+// RecoveringReader is the io.Reader returned by SafeReader.
+type RecoveringReader struct {
+	lastOutcome
+	r io.Reader
+}
+
+// Read calls the Read method of the wrapped reader, turning any panic into an
+// error.
+func (rr *RecoveringReader) Read(p []byte) (n int, err error) {
+	defer rr.recover(&n, &err)
+	return rr.r.Read(p)
+}
+
+// SafeReader returns an io.Reader reading from r, which recovers any panic
+// occurring in the Read method of r, and returns it as an error (an Outcome)
+// instead, e.g. when passing untrusted implementations to functions that do not
+// expect them to panic. The details of the last panic recovered are available
+// via the LastOutcome method of the returned reader.
+func SafeReader(r io.Reader) *RecoveringReader {
+	return &RecoveringReader{r: r}
+}
+
+// RecoveringWriter is the io.Writer returned by SafeWriter.
+type RecoveringWriter struct {
+	lastOutcome
+	w io.Writer
+}
+
+// Write calls the Write method of the wrapped writer, turning any panic into an
+// error.
+func (rw *RecoveringWriter) Write(p []byte) (n int, err error) {
+	defer rw.recover(&n, &err)
+	return rw.w.Write(p)
+}
+
+// SafeWriter returns an io.Writer writing to w, which recovers any panic
+// occurring in the Write method of w, like SafeReader does for readers.
+func SafeWriter(w io.Writer) *RecoveringWriter {
+	return &RecoveringWriter{w: w}
+}
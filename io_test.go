@@ -0,0 +1,71 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+type panicReadWriter struct{}
+
+func (panicReadWriter) Read(p []byte) (int, error) {
+	panic("read")
+}
+
+func (panicReadWriter) Write(p []byte) (int, error) {
+	panic(io.ErrShortWrite)
+}
+
+func TestSafeReader(t *testing.T) {
+	r := SafeReader(strings.NewReader("abc"))
+	if b, err := io.ReadAll(r); string(b) != "abc" || err != nil {
+		t.Errorf(`io.ReadAll(SafeReader(goodReader)) = (%q, %v), want (%q, %v)`, b, err, "abc", nil)
+	}
+	if lo := r.LastOutcome(); lo != nil {
+		t.Errorf(`SafeReader(goodReader).LastOutcome() = %v, want %v`, lo, nil)
+	}
+
+	r = SafeReader(panicReadWriter{})
+	b, err := io.ReadAll(r)
+	lo := r.LastOutcome()
+	if len(b) != 0 || err == nil || err != error(lo) {
+		t.Errorf(`io.ReadAll(SafeReader(panicReader)) = (%q, %v), want the LastOutcome as error`, b, err)
+	}
+	if ol, ot := lo.Level(), lo.Text(); ol != PANIC || ot != "panic: read" {
+		t.Errorf(`SafeReader(panicReader).LastOutcome() = (%q, %q), want (%q, %q)`, LevelName(ol), ot, LevelName(PANIC), "panic: read")
+	}
+}
+
+func TestSafeWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := SafeWriter(&buf)
+	if _, err := fmt.Fprint(w, "abc"); buf.String() != "abc" || err != nil {
+		t.Errorf(`fmt.Fprint(SafeWriter(goodWriter), "abc") wrote %q, %v, want %q, %v`, buf.String(), err, "abc", nil)
+	}
+
+	w = SafeWriter(panicReadWriter{})
+	n, err := io.Copy(w, strings.NewReader("abc"))
+	if n != 0 || !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf(`io.Copy(SafeWriter(panicWriter), ...) = (%d, %v), want (%d, %v)`, n, err, 0, io.ErrShortWrite)
+	}
+	if lo := w.LastOutcome(); lo == nil || lo.Code() != ERR_TRY_PANIC {
+		t.Errorf(`SafeWriter(panicWriter).LastOutcome() = %v, want a recovered panic`, lo)
+	}
+}
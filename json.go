@@ -0,0 +1,88 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// outcomeJSON defines the JSON representation of an Outcome.
+type outcomeJSON struct {
+	Level      string                     `json:"level"`
+	Code       int                        `json:"code"`
+	Text       string                     `json:"text,omitempty"`
+	Fields     map[string]json.RawMessage `json:"fields,omitempty"`
+	Info       []string                   `json:"info,omitempty"`
+	Err        string                     `json:"err,omitempty"`
+	Value      json.RawMessage            `json:"value,omitempty"`
+	Goroutines int                        `json:"goroutines,omitempty"`
+	Duration   time.Duration              `json:"duration,omitempty"`
+	Handled    bool                       `json:"handled,omitempty"`
+	Func       string                     `json:"func,omitempty"`
+	Timestamp  *time.Time                 `json:"timestamp,omitempty"`
+	Host       string                     `json:"host,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, for outbound reporting of Outcomes.
+// The level is represented by its name, and the error returned by the Try-ed
+// function, if any, by its message. The value returned by the Try-ed function
+// is only included if it can itself be marshaled to JSON, while fields that
+// cannot be marshaled are represented by their default string formatting.
+// The duration, if known, is represented in nanoseconds, and the timestamp in
+// RFC 3339 format.
+func (o *Outcome) MarshalJSON() ([]byte, error) {
+	oj := outcomeJSON{
+		Level:      LevelName(o.level),
+		Code:       o.code,
+		Text:       o.text,
+		Info:       o.info,
+		Goroutines: o.goroutines,
+		Duration:   o.duration,
+		Handled:    o.handled,
+		Func:       o.funcName,
+		Host:       o.host,
+	}
+	if !o.time.IsZero() {
+		oj.Timestamp = &o.time
+	}
+	if o.err != nil {
+		oj.Err = o.err.Error()
+	}
+	if len(o.fields) > 0 {
+		oj.Fields = make(map[string]json.RawMessage, len(o.fields))
+		for k, v := range o.fields {
+			b, err := json.Marshal(v)
+			if err != nil {
+				b, _ = json.Marshal(fmt.Sprint(v))
+			}
+			oj.Fields[k] = b
+		}
+	}
+	if o.val != nil {
+		if v, err := json.Marshal(o.val); err == nil {
+			oj.Value = v
+		}
+	}
+	return json.Marshal(oj)
+}
+
+// MarshalText implements encoding.TextMarshaler, for encoders and logging
+// libraries that fall back to it, returning the same text as String. Since the
+// receiver also implements json.Marshaler, JSON encoding is not affected.
+func (o *Outcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	for _, test := range []struct {
+		out *Outcome
+		exp string
+	}{
+		{&Outcome{}, `{"level":"OK","code":0}`},
+		{&Outcome{val: 17, err: fmt.Errorf("test")}, `{"level":"OK","code":0,"err":"test","value":17}`},
+		{&Outcome{val: func() {}}, `{"level":"OK","code":0}`},
+		{&Outcome{level: PANIC, code: 17, text: "abc", info: []string{"x", "y"}, goroutines: 3}, `{"level":"PANIC","code":17,"text":"abc","info":["x","y"],"goroutines":3}`},
+		{&Outcome{level: PANIC, duration: 1500 * time.Millisecond}, `{"level":"PANIC","code":0,"duration":1500000000}`},
+		{(&Outcome{level: PANIC, code: 1, text: "abc"}).Handled(), `{"level":"OK","code":1,"text":"abc","handled":true}`},
+		{&Outcome{level: ERROR, funcName: "main.run"}, `{"level":"ERROR","code":0,"func":"main.run"}`},
+		{&Outcome{level: ERROR, time: time.Date(2015, 6, 1, 12, 30, 0, 0, time.UTC), host: "node-17"}, `{"level":"ERROR","code":0,"timestamp":"2015-06-01T12:30:00Z","host":"node-17"}`},
+		{(&Outcome{level: ERROR}).WithField("id", 17).WithField("c", complex(1, 2)), `{"level":"ERROR","code":0,"fields":{"c":"(1+2i)","id":17}}`},
+	} {
+		b, err := json.Marshal(test.out)
+		if err != nil {
+			t.Errorf(`json.Marshal(%#v) failed: %v`, test.out, err)
+		} else if string(b) != test.exp {
+			t.Errorf(`json.Marshal(%#v) = %s, want %s`, test.out, b, test.exp)
+		}
+	}
+}
+
+func TestMarshalText(t *testing.T) {
+	for name, out := range map[string]*Outcome{
+		"goodFunc":  Try(func() {}),
+		"panicFunc": Try(func() { panic("test") }),
+	} {
+		var tm encoding.TextMarshaler = out
+		if b, err := tm.MarshalText(); string(b) != out.String() || err != nil {
+			t.Errorf(`Try(%s).MarshalText() = (%q, %v), want (%q, %v)`, name, b, err, out.String(), nil)
+		}
+	}
+	out := Try(func() { panic("test") })
+	b, _ := json.Marshal(out)
+	var oj outcomeJSON
+	if err := json.Unmarshal(b, &oj); err != nil || oj.Level != "PANIC" || oj.Text != "panic: test" {
+		t.Errorf(`json.Marshal(Try(panicFunc)) = %s, want the MarshalJSON representation`, b)
+	}
+}
@@ -0,0 +1,397 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LogLeveled sends the non-OK Outcome to the provided leveled logger, passing
+// the Outcome level as the logging level. Since Outcome levels match those of
+// agext/log, PANIC and FATAL map to the highest severities; whether logging at
+// those levels triggers a panic or exits the program is up to the logger.
+func (o *Outcome) LogLeveled(log LeveledLogger) *Outcome {
+	if o.level != OK {
+		log.Log(o.level, o)
+	}
+	return o
+}
+
+// LogKV sends the non-OK Outcome to the provided structured logger, as alternating
+// keys and values: "level" (the level name), "code", "text", followed by the fields
+// of the Outcome (sorted by key) and, if there is any, "info". OK outcomes are not
+// logged, same as with Log. Any error returned by the logger is ignored.
+func (o *Outcome) LogKV(log KVLogger) *Outcome {
+	if o.level == OK {
+		return o
+	}
+	keys := make([]string, 0, len(o.fields))
+	for k := range o.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kv := make([]interface{}, 0, 8+2*len(keys))
+	kv = append(kv, "level", LevelName(o.level), "code", o.code, "text", o.text)
+	for _, k := range keys {
+		kv = append(kv, k, o.fields[k])
+	}
+	if len(o.info) > 0 {
+		kv = append(kv, "info", o.info)
+	}
+	log.Log(kv...)
+	return o
+}
+
+// LogSlog sends the non-OK Outcome to the provided structured logger, as a
+// record with the Outcome text as message, and its level name, code and info
+// as attributes. OK outcomes are not logged, same as with Log.
+//
+// WARN and INFO outcomes are logged at the matching slog levels, and custom
+// levels below ERROR at slog.LevelInfo. Since slog has no levels that terminate
+// the program, all error conditions are logged at slog.LevelError. For PANIC and
+// FATAL conditions, escalate (if not nil) is called after logging, to trigger a
+// panic, exit, or anything else.
+func (o *Outcome) LogSlog(l *slog.Logger, escalate func(*Outcome)) *Outcome {
+	var level slog.Level
+	switch o.level {
+	case OK:
+		return o
+	case INFO:
+		level = slog.LevelInfo
+	case WARN:
+		level = slog.LevelWarn
+	default:
+		level = slog.LevelError
+		if o.level < ERROR {
+			level = slog.LevelInfo
+		}
+	}
+	l.LogAttrs(context.Background(), level, o.text,
+		slog.String("severity", LevelName(o.level)),
+		slog.Int("code", o.code),
+		slog.Any("info", o.info),
+	)
+	if escalate != nil && (o.level == PANIC || o.level == FATAL) {
+		escalate(o)
+	}
+	return o
+}
+
+// defaultLogger is the Logger used by Handle, guarded by defaultLoggerMu.
+var (
+	defaultLogger   Logger = newStderrLogger()
+	defaultLoggerMu sync.RWMutex
+)
+
+// newStderrLogger returns a standard library logger writing to stderr.
+func newStderrLogger() Logger {
+	return log.New(os.Stderr, "", log.LstdFlags)
+}
+
+// SetDefaultLogger sets the Logger used by Handle. Passing nil restores the
+// initial default, a standard library logger writing to stderr.
+func SetDefaultLogger(l Logger) {
+	if l == nil {
+		l = newStderrLogger()
+	}
+	defaultLoggerMu.Lock()
+	defaultLogger = l
+	defaultLoggerMu.Unlock()
+}
+
+// Handle logs the provided Outcome to the default Logger (see SetDefaultLogger),
+// like its Log method does, and returns it. This allows simple programs to write
+// `calmly.Handle(calmly.Try(work))` without passing a Logger around.
+func Handle(o *Outcome) *Outcome {
+	defaultLoggerMu.RLock()
+	l := defaultLogger
+	defaultLoggerMu.RUnlock()
+	return o.Log(l)
+}
+
+// multiLogger forwards logging calls to several Loggers.
+type multiLogger []Logger
+
+// NewMultiLogger returns a Logger that forwards every call to all the provided
+// Loggers, in order. Since the Panic and Fatal methods of a Logger usually do not
+// return, special care is taken for all Loggers to receive the record:
+//   - Panic is forwarded to every Logger, recovering from the panics triggered
+//     by all but the last one, which is allowed to propagate;
+//   - Fatal is forwarded only to the last Logger, while all the others receive
+//     the record via Print, since there is no way to prevent them from exiting.
+//
+// Without any Logger, Panic still panics, and Fatal still exits the program,
+// like the log package does, as expected by the Log method of Outcome.
+func NewMultiLogger(loggers ...Logger) Logger {
+	return multiLogger(loggers)
+}
+
+func (ml multiLogger) Print(v ...interface{}) {
+	for _, l := range ml {
+		l.Print(v...)
+	}
+}
+
+func (ml multiLogger) Panic(v ...interface{}) {
+	if len(ml) == 0 {
+		panic(fmt.Sprint(v...))
+	}
+	for i, l := range ml {
+		if i == len(ml)-1 {
+			l.Panic(v...)
+		} else {
+			func() {
+				defer func() {
+					recover()
+				}()
+				l.Panic(v...)
+			}()
+		}
+	}
+}
+
+func (ml multiLogger) Fatal(v ...interface{}) {
+	if len(ml) == 0 {
+		os.Exit(1)
+	}
+	for i, l := range ml {
+		if i == len(ml)-1 {
+			l.Fatal(v...)
+		} else {
+			l.Print(v...)
+		}
+	}
+}
+
+// discardLogger is the type of Discard.
+type discardLogger struct{}
+
+func (discardLogger) Print(...interface{}) {}
+func (discardLogger) Panic(...interface{}) {}
+func (discardLogger) Fatal(...interface{}) {}
+
+// Discard is a Logger that ignores all calls, including Panic and Fatal,
+// for code paths where logging should be disabled.
+var Discard Logger = discardLogger{}
+
+// LogRecord describes a call received by a RecordingLogger.
+type LogRecord struct {
+	Level   int8
+	Message string
+}
+
+// RecordingLogger is a Logger, and a LeveledLogger, that stores the calls it
+// receives instead of writing them out, for making assertions in tests. Its
+// Panic and Fatal methods only record the call, without panicking or exiting.
+// It is safe for concurrent use.
+type RecordingLogger struct {
+	mu      sync.Mutex
+	records []LogRecord
+}
+
+// NewRecordingLogger returns a new, empty RecordingLogger.
+func NewRecordingLogger() *RecordingLogger {
+	return &RecordingLogger{}
+}
+
+// record stores a call at the provided level.
+func (rl *RecordingLogger) record(level int8, v []interface{}) {
+	rl.mu.Lock()
+	rl.records = append(rl.records, LogRecord{Level: level, Message: fmt.Sprint(v...)})
+	rl.mu.Unlock()
+}
+
+// Print records a call at the level of the Outcome it receives as its only
+// argument, as done by the Log method of Outcome, or at OK level otherwise.
+func (rl *RecordingLogger) Print(v ...interface{}) {
+	level := OK
+	if len(v) == 1 {
+		if o, ok := v[0].(*Outcome); ok && o != nil {
+			level = o.level
+		}
+	}
+	rl.record(level, v)
+}
+
+// Panic records a call at PANIC level.
+func (rl *RecordingLogger) Panic(v ...interface{}) {
+	rl.record(PANIC, v)
+}
+
+// Fatal records a call at FATAL level.
+func (rl *RecordingLogger) Fatal(v ...interface{}) {
+	rl.record(FATAL, v)
+}
+
+// Log records a call at the provided level.
+func (rl *RecordingLogger) Log(level int8, v ...interface{}) {
+	rl.record(level, v)
+}
+
+// Records returns a copy of the calls recorded so far, in order.
+func (rl *RecordingLogger) Records() []LogRecord {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return append([]LogRecord(nil), rl.records...)
+}
+
+// Messages returns the messages recorded so far, in order.
+func (rl *RecordingLogger) Messages() []string {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	msgs := make([]string, len(rl.records))
+	for i, r := range rl.records {
+		msgs[i] = r.Message
+	}
+	return msgs
+}
+
+// Reset discards the calls recorded so far.
+func (rl *RecordingLogger) Reset() {
+	rl.mu.Lock()
+	rl.records = nil
+	rl.mu.Unlock()
+}
+
+// dedupEntry tracks the recent occurrences of a message in a dedupLogger.
+type dedupEntry struct {
+	msg        string
+	since      time.Time
+	suppressed int
+	timer      *time.Timer
+}
+
+// dedupLogger suppresses repeated messages within a time window.
+type dedupLogger struct {
+	inner   Logger
+	window  time.Duration
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+// NewDedupLogger returns a Logger that forwards calls to inner, except for those
+// repeating, within the given time window, a message already forwarded; Outcomes
+// are considered the same if they have the same text and code. When the window
+// expires, a summary line with the number of suppressed occurrences, if any, is
+// forwarded (via Print), and the next occurrence of the message is forwarded
+// again.
+//
+// Fatal calls are always forwarded, since they terminate the program, after the
+// summaries of all the messages suppressed so far. Suppressed Panic calls still
+// panic, like log.Panic does, but without logging the message.
+func NewDedupLogger(inner Logger, window time.Duration) Logger {
+	return &dedupLogger{inner: inner, window: window, entries: make(map[string]*dedupEntry)}
+}
+
+// dedupKey returns the key identifying the message made of v.
+func dedupKey(v []interface{}) string {
+	if len(v) == 1 {
+		if o, ok := v[0].(*Outcome); ok && o != nil {
+			return fmt.Sprintf("%d\x00%s", o.code, o.text)
+		}
+	}
+	return fmt.Sprint(v...)
+}
+
+// summary returns the summary line of the occurrences of e suppressed since the
+// last one, if any, and resets their count. It must be called with dl.mu held.
+func (e *dedupEntry) summary() string {
+	if e.suppressed == 0 {
+		return ""
+	}
+	s := fmt.Sprintf("%s [%d duplicates suppressed]", e.msg, e.suppressed)
+	e.suppressed = 0
+	return s
+}
+
+// expire removes the entry e stored under key, if still there, and forwards its
+// summary, if any.
+func (dl *dedupLogger) expire(key string, e *dedupEntry) {
+	dl.mu.Lock()
+	if dl.entries[key] == e {
+		delete(dl.entries, key)
+	}
+	s := e.summary()
+	dl.mu.Unlock()
+	if s != "" {
+		dl.inner.Print(s)
+	}
+}
+
+// allow reports whether the message made of v is to be forwarded, after
+// forwarding the summary of its previous window, if the timer has yet to.
+func (dl *dedupLogger) allow(v []interface{}) bool {
+	key, now := dedupKey(v), time.Now()
+	var summary string
+	dl.mu.Lock()
+	if e, ok := dl.entries[key]; ok {
+		if now.Sub(e.since) < dl.window {
+			e.suppressed++
+			dl.mu.Unlock()
+			return false
+		}
+		e.timer.Stop()
+		summary = e.summary()
+	}
+	e := &dedupEntry{msg: fmt.Sprint(v...), since: now}
+	e.timer = time.AfterFunc(dl.window, func() { dl.expire(key, e) })
+	dl.entries[key] = e
+	dl.mu.Unlock()
+	if summary != "" {
+		dl.inner.Print(summary)
+	}
+	return true
+}
+
+// flush forwards the summaries of all the messages suppressed so far.
+func (dl *dedupLogger) flush() {
+	var summaries []string
+	dl.mu.Lock()
+	for _, e := range dl.entries {
+		if s := e.summary(); s != "" {
+			summaries = append(summaries, s)
+		}
+	}
+	dl.mu.Unlock()
+	for _, s := range summaries {
+		dl.inner.Print(s)
+	}
+}
+
+func (dl *dedupLogger) Print(v ...interface{}) {
+	if dl.allow(v) {
+		dl.inner.Print(v...)
+	}
+}
+
+func (dl *dedupLogger) Panic(v ...interface{}) {
+	if dl.allow(v) {
+		dl.inner.Panic(v...)
+		return
+	}
+	panic(fmt.Sprint(v...))
+}
+
+func (dl *dedupLogger) Fatal(v ...interface{}) {
+	dl.flush()
+	dl.inner.Fatal(v...)
+}
@@ -0,0 +1,224 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type mockLeveledLogger struct {
+	log string
+}
+
+func (ml *mockLeveledLogger) Log(level int8, s ...interface{}) {
+	ml.log += "[" + LevelName(level) + "] " + fmt.Sprintln(s...)
+}
+
+func TestLogLeveled(t *testing.T) {
+	log := &mockLeveledLogger{}
+	out := &Outcome{text: "abc"}
+	out.LogLeveled(log).SetLevel(WARN).LogLeveled(log).SetLevel(ERROR).LogLeveled(log).SetLevel(PANIC).LogLeveled(log).SetLevel(FATAL).SetCode(17).LogLeveled(log)
+	exp := "[WARN] abc\n[ERROR] abc\n[PANIC] abc\n[FATAL] abc (code: 0x0011)\n"
+	if log.log != exp {
+		t.Errorf(`leveled logging test got %q, want %q`, log.log, exp)
+	}
+}
+
+func TestLogSlog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	escalated := 0
+	escalate := func(*Outcome) { escalated++ }
+	out := &Outcome{text: "abc"}
+	out.LogSlog(l, escalate).SetLevel(INFO).LogSlog(l, escalate).SetLevel(WARN).LogSlog(l, escalate).SetLevel(ERROR).LogSlog(l, escalate).SetLevel(PANIC).LogSlog(l, escalate).SetLevel(FATAL).SetCode(17).AddInfo("x").LogSlog(l, nil)
+	exp := "level=INFO msg=abc severity=INFO code=0 info=[]\n" +
+		"level=WARN msg=abc severity=WARN code=0 info=[]\n" +
+		"level=ERROR msg=abc severity=ERROR code=0 info=[]\n" +
+		"level=ERROR msg=abc severity=PANIC code=0 info=[]\n" +
+		"level=ERROR msg=abc severity=FATAL code=17 info=[x]\n"
+	if buf.String() != exp {
+		t.Errorf(`slog logging test got %q, want %q`, buf.String(), exp)
+	}
+	if escalated != 1 {
+		t.Errorf(`slog logging test escalated %d times, want %d`, escalated, 1)
+	}
+}
+
+type panickingLogger struct {
+	mockLogger
+}
+
+func (pl *panickingLogger) Panic(s ...interface{}) {
+	pl.mockLogger.Panic(s...)
+	panic(fmt.Sprint(s...))
+}
+
+func TestMultiLogger(t *testing.T) {
+	log1, log2, log3 := &panickingLogger{}, &mockLogger{}, &panickingLogger{}
+	log := NewMultiLogger(log1, log2, log3)
+	out := Try(func() {
+		(&Outcome{text: "abc"}).SetLevel(ERROR).Log(log).SetLevel(FATAL).Log(log).SetLevel(PANIC).Log(log)
+	})
+	if pv := out.PanicValue(); pv != "abc" {
+		t.Errorf(`multi logger panic = %v, want %v`, pv, "abc")
+	}
+	for name, test := range map[string]struct {
+		got, exp string
+	}{
+		"log1": {log1.log, "abc\nabc\n[PANIC] abc\n"},
+		"log2": {log2.log, "abc\nabc\n[PANIC] abc\n"},
+		"log3": {log3.log, "abc\n[FATAL] abc\n[PANIC] abc\n"},
+	} {
+		if test.got != test.exp {
+			t.Errorf(`multi logger test got %q for %s, want %q`, test.got, name, test.exp)
+		}
+	}
+
+	out = Try(func() {
+		(&Outcome{level: PANIC, text: "abc"}).Log(NewMultiLogger())
+	})
+	if pv := out.PanicValue(); pv != "abc" {
+		t.Errorf(`empty multi logger panic = %v, want %v`, pv, "abc")
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	out := &Outcome{text: "abc"}
+	for _, level := range []int8{OK, INFO, WARN, ERROR, PANIC, FATAL} {
+		out.SetLevel(level).Log(Discard)
+	}
+}
+
+func TestRecordingLogger(t *testing.T) {
+	rl := NewRecordingLogger()
+	out := &Outcome{text: "abc"}
+	out.Log(rl).SetLevel(WARN).Log(rl).SetLevel(ERROR).Log(rl).SetLevel(PANIC).Log(rl).SetLevel(FATAL).SetCode(17).Log(rl).SetLevel(WARN).LogLeveled(rl)
+	rl.Print("x", 1)
+	exp := []LogRecord{
+		{WARN, "abc"},
+		{ERROR, "abc"},
+		{PANIC, "abc"},
+		{FATAL, "abc (code: 0x0011)"},
+		{WARN, "abc (code: 0x0011)"},
+		{OK, "x1"},
+	}
+	if got := rl.Records(); !reflect.DeepEqual(got, exp) {
+		t.Errorf(`RecordingLogger.Records() = %v, want %v`, got, exp)
+	}
+	if got := rl.Messages(); len(got) != len(exp) || got[0] != "abc" || got[5] != "x1" {
+		t.Errorf(`RecordingLogger.Messages() = %q, not matching the records`, got)
+	}
+	rl.Reset()
+	if got := rl.Records(); len(got) != 0 {
+		t.Errorf(`RecordingLogger.Reset().Records() = %v, want none`, got)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	defer SetDefaultLogger(nil)
+	rl := NewRecordingLogger()
+	SetDefaultLogger(rl)
+	out := &Outcome{level: WARN, text: "abc"}
+	if ret := Handle(out); ret != out {
+		t.Errorf(`Handle(out) should return its argument`)
+	}
+	Handle(&Outcome{})
+	if got, exp := rl.Records(), []LogRecord{{WARN, "abc"}}; !reflect.DeepEqual(got, exp) {
+		t.Errorf(`Handle(out) logged %v, want %v`, got, exp)
+	}
+	SetDefaultLogger(nil)
+	if _, ok := defaultLogger.(*log.Logger); !ok {
+		t.Errorf(`SetDefaultLogger(nil) set the default Logger to %T, want %T`, defaultLogger, &log.Logger{})
+	}
+}
+
+func TestDedupLogger(t *testing.T) {
+	rl := NewRecordingLogger()
+	dl := NewDedupLogger(rl, 30*time.Millisecond)
+	out := &Outcome{level: ERROR, code: 17, text: "abc"}
+	for i := 0; i < 3; i++ {
+		out.Log(dl)
+		(&Outcome{level: ERROR, code: 17, text: "abc"}).Log(dl)
+	}
+	(&Outcome{level: ERROR, code: 18, text: "abc"}).Log(dl)
+	out.Clone().SetLevel(FATAL).Log(dl)
+	if got := Try(func() { out.Clone().SetLevel(PANIC).Log(dl) }); got.Level() != PANIC {
+		t.Errorf(`suppressed Panic did not panic`)
+	}
+	time.Sleep(40 * time.Millisecond)
+	out.Log(dl)
+	exp := []LogRecord{
+		{ERROR, "abc (code: 0x0011)"},
+		{ERROR, "abc (code: 0x0012)"},
+		{OK, "abc (code: 0x0011) [5 duplicates suppressed]"},
+		{FATAL, "abc (code: 0x0011)"},
+		{OK, "abc (code: 0x0011) [1 duplicates suppressed]"},
+		{ERROR, "abc (code: 0x0011)"},
+	}
+	if got := rl.Records(); !reflect.DeepEqual(got, exp) {
+		t.Errorf(`NewDedupLogger(rl, 30ms) forwarded %v, want %v`, got, exp)
+	}
+
+	rl.Reset()
+	for i := 0; i < 5; i++ {
+		(&Outcome{level: WARN, code: 19, text: "def"}).Log(dl)
+	}
+	exp = []LogRecord{
+		{WARN, "def (code: 0x0013)"},
+		{OK, "def (code: 0x0013) [4 duplicates suppressed]"},
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(rl.Records()) < len(exp) && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := rl.Records(); !reflect.DeepEqual(got, exp) {
+		t.Errorf(`NewDedupLogger(rl, 30ms) forwarded %v once the window expired, want %v`, got, exp)
+	}
+}
+
+type mockKVLogger struct {
+	calls [][]interface{}
+}
+
+func (ml *mockKVLogger) Log(keyvals ...interface{}) error {
+	ml.calls = append(ml.calls, keyvals)
+	return nil
+}
+
+func TestLogKV(t *testing.T) {
+	log := &mockKVLogger{}
+	(&Outcome{text: "abc"}).LogKV(log)
+	out := (&Outcome{level: ERROR, code: 17, text: "abc"}).WithField("user", "jane").WithField("id", 3)
+	out.LogKV(log).AddInfo("line 1").LogKV(log)
+	exp := [][]interface{}{
+		{"level", "ERROR", "code", 17, "text", "abc", "id", 3, "user", "jane"},
+		{"level", "ERROR", "code", 17, "text", "abc", "id", 3, "user", "jane", "info", []string{"line 1"}},
+	}
+	if !reflect.DeepEqual(log.calls, exp) {
+		t.Errorf(`LogKV(log) logged %v, want %v`, log.calls, exp)
+	}
+}
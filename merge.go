@@ -0,0 +1,77 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Merge combines several Outcomes into a single one, at the highest level among
+// them, holding the info of all of them. Nil Outcomes are ignored.
+//
+// If only one of the Outcomes is not at OK level, the merged Outcome takes its
+// code and text. If several are, it gets the ERR_MERGED code, and a text listing
+// each of them. If all the Outcomes are OK, so is the merged one.
+//
+// The merged Outcome retains the Outcomes it combines, available via Children.
+func Merge(outcomes ...*Outcome) *Outcome {
+	m := (&Outcome{level: OK}).stamp(time.Now())
+	var failed []*Outcome
+	for _, o := range outcomes {
+		if o == nil {
+			continue
+		}
+		if SeverityLess(m.level, o.level) {
+			m.level = o.level
+		}
+		if o.level != OK {
+			failed = append(failed, o)
+		}
+		m.appendInfo(o)
+		m.children = append(m.children, o)
+	}
+	switch len(failed) {
+	case 0:
+	case 1:
+		m.code, m.text = failed[0].code, failed[0].text
+	default:
+		texts := make([]string, len(failed))
+		for i, o := range failed {
+			texts[i] = o.Error()
+		}
+		m.code = ERR_MERGED
+		m.text = fmt.Sprintf("%d failures: %s", len(failed), strings.Join(texts, "; "))
+	}
+	return m
+}
+
+// Children returns the Outcomes combined into the receiver by Merge, or nil if
+// the receiver was not produced by Merge.
+func (o *Outcome) Children() []*Outcome {
+	return o.children
+}
+
+// Walk calls the provided function for the receiver, then for each of its
+// children (see Children), depth-first, including those of nested merged
+// Outcomes, e.g. to report each underlying failure separately.
+func (o *Outcome) Walk(f func(*Outcome)) *Outcome {
+	f(o)
+	for _, c := range o.children {
+		c.Walk(f)
+	}
+	return o
+}
@@ -0,0 +1,74 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	good := &Outcome{val: 17}
+	warn := &Outcome{level: WARN, text: "careful", info: []string{"w"}}
+	fail := &Outcome{level: ERROR, code: 17, text: "failed", info: []string{"e1", "e2"}}
+	panicked := &Outcome{level: PANIC, code: ERR_TRY_PANIC, text: "panic: test", info: []string{"p"}}
+
+	for _, test := range []struct {
+		name     string
+		outcomes []*Outcome
+		level    int8
+		code     int
+		text     string
+		info     int
+	}{
+		{"none", nil, OK, 0, "", 0},
+		{"good", []*Outcome{good, nil, good}, OK, 0, "", 0},
+		{"single", []*Outcome{good, fail}, ERROR, 17, "failed", 2},
+		{"several", []*Outcome{warn, good, panicked, fail}, PANIC, ERR_MERGED, "3 failures: careful; panic: test (code: 0x0001); failed (code: 0x0011)", 4},
+	} {
+		out := Merge(test.outcomes...)
+		if ol, oc := out.Level(), out.Code(); ol != test.level || oc != test.code {
+			t.Errorf(`Merge(%s) = (%q, 0x%04x), want (%q, 0x%04x)`, test.name, LevelName(ol), oc, LevelName(test.level), test.code)
+		}
+		if ot := out.Text(); ot != test.text {
+			t.Errorf(`Merge(%s).Text() = %q, want %q`, test.name, ot, test.text)
+		}
+		if oi := out.Info(); len(oi) != test.info {
+			t.Errorf(`len(Merge(%s).Info()) = %d, want %d`, test.name, len(oi), test.info)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	a, b, c := &Outcome{text: "a"}, &Outcome{level: ERROR, text: "b"}, &Outcome{level: PANIC, text: "c"}
+	inner := Merge(b, nil, c)
+	outer := Merge(a, inner)
+	if oc := outer.Children(); len(oc) != 2 || oc[0] != a || oc[1] != inner {
+		t.Errorf(`Merge(a, inner).Children() = %v, want [a inner]`, oc)
+	}
+	if oc := a.Children(); oc != nil {
+		t.Errorf(`a.Children() = %v, want %v`, oc, nil)
+	}
+	var visited []*Outcome
+	if ret := outer.Walk(func(o *Outcome) { visited = append(visited, o) }); ret != outer {
+		t.Errorf(`Walk(f) should return its receiver`)
+	}
+	if exp := []*Outcome{outer, a, inner, b, c}; !reflect.DeepEqual(visited, exp) {
+		t.Errorf(`Merge(a, Merge(b, c)).Walk(f) visited %v, want %v`, visited, exp)
+	}
+	if cc := outer.Clone().Children(); len(cc) != 2 || cc[1] != inner {
+		t.Errorf(`Merge(a, inner).Clone().Children() = %v, want [a inner]`, cc)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+// Option configures optional behavior of `Try`.
+type Option func(*config)
+
+// config holds the settings accumulated from the Options passed to `Try`.
+type config struct {
+	goroutines bool
+	hasCode    bool
+	code       int
+	noStack    bool
+	panicInfo  []func() []string
+	funcName   string
+	onGoexit   func(*Outcome)
+	deferHook  bool
+	mapper     func(recovered interface{}) *Outcome
+}
+
+// newConfig applies the provided options to a default config.
+func newConfig(opts []Option) config {
+	if len(opts) == 0 {
+		// avoid allocating a config for the options to modify
+		return config{}
+	}
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return *c
+}
+
+// WithGoroutineCount makes `Try` record the number of goroutines existing at
+// the time a panic is recovered, available via the Goroutines method of the
+// Outcome.
+func WithGoroutineCount() Option {
+	return func(c *config) {
+		c.goroutines = true
+	}
+}
+
+// WithCode makes `Try` store the provided code in the Outcome, instead of the
+// default one, if a panic or error condition occurs.
+func WithCode(code int) Option {
+	return func(c *config) {
+		c.hasCode, c.code = true, code
+	}
+}
+
+// WithoutStack makes `Try` skip adding a stack trace to the info of the Outcome
+// if a panic is recovered, as if CaptureStack was false.
+func WithoutStack() Option {
+	return func(c *config) {
+		c.noStack = true
+	}
+}
+
+// OnPanicInfo makes `Try` call the provided function if a panic is recovered,
+// and add the lines it returns to the info of the Outcome, e.g. to report some
+// state at the time of the panic, without computing it when no panic occurs.
+// If the function itself panics, a line describing that panic is added instead.
+func OnPanicInfo(f func() []string) Option {
+	return func(c *config) {
+		c.panicInfo = append(c.panicInfo, f)
+	}
+}
+
+// withFuncName makes `Try` record the provided name as that of the Try-ed function,
+// for wrappers like TryArgs that call it through a closure.
+func withFuncName(name string) Option {
+	return func(c *config) {
+		c.funcName = name
+	}
+}
+
+// withGoexit makes `Try` pass the Outcome to the provided function if the Try-ed
+// function calls runtime.Goexit, since Try cannot return it in that case.
+func withGoexit(f func(*Outcome)) Option {
+	return func(c *config) {
+		c.onGoexit = f
+	}
+}
+
+// withDeferredNotify makes `Try` leave it to the caller to pass the Outcome to
+// OnOutcome, for wrappers like TryEach that add to it before returning it, except
+// if the Try-ed function calls runtime.Goexit, since Try does not return then.
+func withDeferredNotify() Option {
+	return func(c *config) {
+		c.deferHook = true
+	}
+}
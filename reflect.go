@@ -0,0 +1,151 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"reflect"
+	"runtime"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// TryArgs calls the function f with the provided arguments, recovering from any
+// panic it may cause, like `Try` does. The function may return nothing, a single
+// value or error, or a value and an error, in either order; any result whose type
+// implements error is stored as the error. For variadic functions, the arguments
+// following the regular parameters are passed as the variadic ones, unless there
+// is a single one, of the slice type of the variadic parameter, which is then
+// passed as a whole, as in f(xs...).
+//
+// The name of f is recorded in the Outcome, and available via its Func method.
+//
+// If f is not a function of a supported shape, or the arguments do not match its
+// parameters in number and type, an Outcome at ERROR level, with code ERR_TRY_ARG,
+// is returned without calling f.
+func TryArgs(f interface{}, args ...interface{}) *Outcome {
+	fv := reflect.ValueOf(f)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return argOutcome("TryArgs: unsupported argument type %T", f)
+	}
+	ft := fv.Type()
+	errIdx, valIdx, ok := resultIndexes(ft)
+	if !ok {
+		return argOutcome("TryArgs: unsupported argument type %T", f)
+	}
+	numIn := ft.NumIn()
+	if ft.IsVariadic() {
+		if len(args) < numIn-1 {
+			return argOutcome("TryArgs: %T expects at least %d arguments, got %d", f, numIn-1, len(args))
+		}
+	} else if numIn != len(args) {
+		return argOutcome("TryArgs: %T expects %d arguments, got %d", f, numIn, len(args))
+	}
+	// an existing slice for the variadic parameter is passed as is
+	spread := ft.IsVariadic() && len(args) == numIn && args[numIn-1] != nil &&
+		reflect.TypeOf(args[numIn-1]).AssignableTo(ft.In(numIn-1))
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var pt reflect.Type
+		if ft.IsVariadic() && i >= numIn-1 && !spread {
+			// the trailing arguments are spread over the variadic parameter
+			pt = ft.In(numIn - 1).Elem()
+		} else {
+			pt = ft.In(i)
+		}
+		if arg == nil {
+			switch pt.Kind() {
+			case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+				in[i] = reflect.Zero(pt)
+				continue
+			}
+			return argOutcome("TryArgs: argument %d is nil, not assignable to %s", i, pt)
+		}
+		in[i] = reflect.ValueOf(arg)
+		if !in[i].Type().AssignableTo(pt) {
+			return argOutcome("TryArgs: argument %d of type %T is not assignable to %s", i, arg, pt)
+		}
+	}
+
+	var name string
+	if rf := runtime.FuncForPC(fv.Pointer()); rf != nil {
+		name = rf.Name()
+	}
+	call := fv.Call
+	if spread {
+		call = fv.CallSlice
+	}
+	return Try(func() (interface{}, error) {
+		return callResults(call(in), errIdx, valIdx)
+	}, withFuncName(name))
+}
+
+// resultIndexes returns the positions of the error and of the value among the
+// results of functions of type ft, or -1 for a missing one. At most one result
+// may implement error, and at most one may not; otherwise, ok is false.
+func resultIndexes(ft reflect.Type) (errIdx, valIdx int, ok bool) {
+	errIdx, valIdx = -1, -1
+	if ft.NumOut() > 2 {
+		return
+	}
+	for i := 0; i < ft.NumOut(); i++ {
+		if ft.Out(i).Implements(errorType) {
+			if errIdx >= 0 {
+				return
+			}
+			errIdx = i
+		} else {
+			if valIdx >= 0 {
+				return
+			}
+			valIdx = i
+		}
+	}
+	return errIdx, valIdx, true
+}
+
+// callResults maps the results of a reflective call to a value and an error,
+// as positioned according to resultIndexes. A nil pointer (or other nillable
+// value) of a concrete type implementing error is mapped to a nil error.
+func callResults(out []reflect.Value, errIdx, valIdx int) (val interface{}, err error) {
+	if valIdx >= 0 {
+		val = out[valIdx].Interface()
+	}
+	if errIdx >= 0 {
+		switch ev := out[errIdx]; ev.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+			if ev.IsNil() {
+				return
+			}
+		}
+		err = out[errIdx].Interface().(error)
+	}
+	return
+}
+
+// reflectFunc returns a function calling f, which must take no arguments, and
+// mapping its results like TryArgs does, or nil if f is not such a function.
+func reflectFunc(f interface{}) func() (interface{}, error) {
+	fv := reflect.ValueOf(f)
+	if fv.Kind() != reflect.Func || fv.IsNil() || fv.Type().NumIn() != 0 {
+		return nil
+	}
+	errIdx, valIdx, ok := resultIndexes(fv.Type())
+	if !ok {
+		return nil
+	}
+	return func() (interface{}, error) {
+		return callResults(fv.Call(nil), errIdx, valIdx)
+	}
+}
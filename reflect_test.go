@@ -0,0 +1,96 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTryArgs(t *testing.T) {
+	called := false
+	for _, test := range []struct {
+		name  string
+		f     interface{}
+		args  []interface{}
+		level int8
+		code  int
+		val   interface{}
+		err   error
+		text  string
+	}{
+		{"noReturn", func(a int) { called = a == 17 }, []interface{}{17}, OK, 0, nil, nil, ""},
+		{"errReturn", func(w io.Writer) error { return io.EOF }, []interface{}{nil}, OK, 0, nil, io.EOF, ""},
+		{"valReturn", func(a, b int) int { return a / b }, []interface{}{34, 2}, OK, 0, 17, nil, ""},
+		{"valErrReturn", func(s string) (string, error) { return s + "!", nil }, []interface{}{"abc"}, OK, 0, "abc!", nil, ""},
+		{"ifaceArg", func(e error) string { return e.Error() }, []interface{}{io.EOF}, OK, 0, "EOF", nil, ""},
+		{"variadic", func(s string, a ...int) int { return len(s) + len(a) }, []interface{}{"abc", 1, 2}, OK, 0, 5, nil, ""},
+		{"variadicNone", func(s string, a ...int) int { return len(s) + len(a) }, []interface{}{"abc"}, OK, 0, 3, nil, ""},
+		{"variadicIface", func(a ...interface{}) int { return len(a) }, []interface{}{1, "b", nil}, OK, 0, 3, nil, ""},
+		{"variadicSlice", func(s string, a ...int) int { return len(s) + a[1] }, []interface{}{"abc", []int{1, 2}}, OK, 0, 5, nil, ""},
+		{"variadicSliceOnly", func(a ...int) int { return len(a) }, []interface{}{[]int{1, 2, 3}}, OK, 0, 3, nil, ""},
+		{"variadicSliceMixed", func(a ...int) {}, []interface{}{1, []int{2}}, ERROR, ERR_TRY_ARG, nil, nil, "argument 1 of type []int is not assignable to int"},
+		{"variadicCount", func(s string, a ...int) {}, nil, ERROR, ERR_TRY_ARG, nil, nil, "expects at least 1 arguments, got 0"},
+		{"variadicType", func(s string, a ...int) {}, []interface{}{"abc", 1, "b"}, ERROR, ERR_TRY_ARG, nil, nil, "argument 2 of type string is not assignable to int"},
+		{"panic", func(a, b int) int { return a / b }, []interface{}{1, 0}, PANIC, ERR_TRY_PANIC, nil, nil, "divide by zero"},
+		{"notFunc", 17, nil, ERROR, ERR_TRY_ARG, nil, nil, "unsupported argument type int"},
+		{"nilFunc", (func())(nil), nil, ERROR, ERR_TRY_ARG, nil, nil, "unsupported argument type func()"},
+		{"badReturn", func() (int, int) { return 1, 2 }, nil, ERROR, ERR_TRY_ARG, nil, nil, "unsupported argument type"},
+		{"argCount", func(a int) {}, []interface{}{1, 2}, ERROR, ERR_TRY_ARG, nil, nil, "expects 1 arguments, got 2"},
+		{"argType", func(a int) {}, []interface{}{"a"}, ERROR, ERR_TRY_ARG, nil, nil, "argument 0 of type string is not assignable to int"},
+		{"nilArg", func(a int) {}, []interface{}{nil}, ERROR, ERR_TRY_ARG, nil, nil, "argument 0 is nil"},
+	} {
+		out := TryArgs(test.f, test.args...)
+		if ol, oc := out.Level(), out.Code(); ol != test.level || oc != test.code {
+			t.Errorf(`TryArgs(%s) = (%q, 0x%04x), want (%q, 0x%04x)`, test.name, LevelName(ol), oc, LevelName(test.level), test.code)
+		}
+		if pe, ok := out.PanicValue().(error); ok {
+			test.err = pe
+		}
+		if ov, oe := out.Result(); ov != test.val || oe != test.err {
+			t.Errorf(`TryArgs(%s).Result() = (%v, %v), want (%v, %v)`, test.name, ov, oe, test.val, test.err)
+		}
+		if ot := out.Text(); !strings.Contains(ot, test.text) {
+			t.Errorf(`TryArgs(%s).Text() does not contain %q (got %q)`, test.name, test.text, ot)
+		}
+	}
+	if !called {
+		t.Errorf(`TryArgs(noReturn, 17) did not call the function with its argument`)
+	}
+}
+
+func testNamedFunc(a int) int {
+	return 10 / a
+}
+
+func TestTryArgsFunc(t *testing.T) {
+	if of := TryArgs(testNamedFunc, 2).Func(); of != "github.com/agext/calmly.testNamedFunc" {
+		t.Errorf(`TryArgs(testNamedFunc, 2).Func() = %q, want %q`, of, "github.com/agext/calmly.testNamedFunc")
+	}
+	var seen string
+	defer setOnOutcome(func(o *Outcome) {
+		seen = o.Func()
+	})()
+	if of := TryArgs(testNamedFunc, 0).Func(); of != "github.com/agext/calmly.testNamedFunc" || seen != of {
+		t.Errorf(`TryArgs(testNamedFunc, 0).Func() = %q (seen by OnOutcome: %q), want %q`, of, seen, "github.com/agext/calmly.testNamedFunc")
+	}
+	if of := TryArgs(func() {}).Func(); !strings.HasPrefix(of, "github.com/agext/calmly.TestTryArgsFunc.func") {
+		t.Errorf(`TryArgs(closure).Func() = %q, want the closure name`, of)
+	}
+	if of := Try(func() {}).Func(); of != "" {
+		t.Errorf(`Try(goodFunc).Func() = %q, want %q`, of, "")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Retry calls f, recovering from any panic it may cause like `Try` does, until
+// it succeeds (i.e. returns nil without panicking), or it has been called the
+// given number of times, waiting for backoff between calls.
+//
+// It returns the Outcome of the last call; if that one failed, the number of
+// attempts is added to its info. Only that Outcome is passed to OnOutcome.
+func Retry(attempts int, backoff time.Duration, f func() error) *Outcome {
+	return RetryExp(attempts, backoff, 1, f)
+}
+
+// RetryExp works like Retry, except that the wait between calls is multiplied
+// by factor after each failed attempt, for an exponential backoff.
+func RetryExp(attempts int, backoff time.Duration, factor float64, f func() error) (o *Outcome) {
+	for n := 1; ; n++ {
+		o = Try(f, withDeferredNotify())
+		if o.level == OK && o.err == nil {
+			return
+		}
+		if n >= attempts {
+			o.AddInfo(fmt.Sprintf("attempts: %d", n))
+			notify(o)
+			return
+		}
+		time.Sleep(backoff)
+		backoff = time.Duration(float64(backoff) * factor)
+	}
+}
+
+// RetryPolicy configures TryRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of calls; values below 1 mean 1.
+	MaxAttempts int
+	// BaseDelay is the wait after the first failed attempt.
+	BaseDelay time.Duration
+	// Multiplier scales the wait after each failed attempt; values of 0 or
+	// less mean 1, for a constant wait.
+	Multiplier float64
+	// MaxDelay, if positive, caps the wait between attempts (before jitter).
+	MaxDelay time.Duration
+	// Jitter randomizes each wait by up to the given fraction of it, in either
+	// direction; e.g. 0.2 makes a 100ms wait last between 80ms and 120ms.
+	// It is clamped to the [0, 1] range.
+	Jitter float64
+	// MaxElapsed, if positive, stops retrying when waiting for the next attempt
+	// would exceed that duration since the first attempt started.
+	MaxElapsed time.Duration
+}
+
+// delay returns the wait after the given failed attempt, counting from 1,
+// without jitter.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	m := p.Multiplier
+	if m <= 0 {
+		m = 1
+	}
+	d := float64(p.BaseDelay)
+	for i := 1; i < attempt; i++ {
+		d *= m
+		if p.MaxDelay > 0 && d >= float64(p.MaxDelay) {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	return time.Duration(d)
+}
+
+// wait returns the wait after the given failed attempt, counting from 1,
+// with jitter applied.
+func (p RetryPolicy) wait(attempt int) time.Duration {
+	d := p.delay(attempt)
+	j := p.Jitter
+	if j > 1 {
+		j = 1
+	}
+	if j <= 0 || d <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + j*(2*rand.Float64()-1)))
+}
+
+// TryRetry calls f, recovering from any panic it may cause like `Try` does, until
+// it succeeds (i.e. returns nil without panicking), or the retry policy says to
+// give up, or ctx is done. If ctx is already done, f is not called at all, and an
+// Outcome with the ERR_TRY_CONTEXT code is returned, same as with TryContext.
+//
+// It returns the Outcome of the last call; if that one failed, the number of
+// attempts and the total elapsed time are added to its info, as well as the
+// context error if ctx was done while waiting for the next attempt. Only that
+// Outcome is passed to OnOutcome.
+func TryRetry(ctx context.Context, policy RetryPolicy, f func() error) (o *Outcome) {
+	if err := ctx.Err(); err != nil {
+		return contextOutcome(err)
+	}
+	start, n := time.Now(), 0
+	giveUp := func(s ...string) *Outcome {
+		o.AddInfo(append([]string{
+			fmt.Sprintf("attempts: %d", n),
+			fmt.Sprintf("elapsed: %s", time.Since(start)),
+		}, s...)...)
+		notify(o)
+		return o
+	}
+	for {
+		n++
+		o = Try(f, withDeferredNotify())
+		if o.level == OK && o.err == nil {
+			return
+		}
+		if n >= policy.MaxAttempts {
+			return giveUp()
+		}
+		wait := policy.wait(n)
+		if policy.MaxElapsed > 0 && time.Since(start)+wait > policy.MaxElapsed {
+			return giveUp()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return giveUp("TryRetry: " + ctx.Err().Error())
+		}
+	}
+}
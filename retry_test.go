@@ -0,0 +1,191 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	out := Retry(5, time.Millisecond, func() error {
+		calls++
+		switch calls {
+		case 1:
+			panic("transient")
+		case 2:
+			return fmt.Errorf("transient")
+		}
+		return nil
+	})
+	if ol, oe := out.Level(), out.Err(); ol != OK || oe != nil || calls != 3 {
+		t.Errorf(`Retry(5, flaky) = (%q, %v) after %d calls, want (%q, %v) after %d calls`, LevelName(ol), oe, calls, LevelName(OK), nil, 3)
+	}
+	if oi := out.Info(); len(oi) != 0 {
+		t.Errorf(`len(Retry(5, flaky).Info()) = %d, want %d`, len(oi), 0)
+	}
+
+	calls = 0
+	out = Retry(3, time.Millisecond, func() error {
+		calls++
+		panic(calls)
+	})
+	if ol := out.Level(); ol != PANIC || calls != 3 {
+		t.Errorf(`Retry(3, panicFunc) = %q after %d calls, want %q after %d calls`, LevelName(ol), calls, LevelName(PANIC), 3)
+	}
+	if opv := out.PanicValue(); opv != 3 {
+		t.Errorf(`Retry(3, panicFunc).PanicValue() = %v, want %v`, opv, 3)
+	}
+	if oi := out.Info(); len(oi) != 2 || oi[1] != "attempts: 3" {
+		t.Errorf(`Retry(3, panicFunc).Info() = %q, want the stack trace and %q`, oi, "attempts: 3")
+	}
+
+	var mu sync.Mutex
+	var hooked [][]string
+	restore := setOnOutcome(func(o *Outcome) {
+		if o.PanicValue() == "TestRetry" {
+			mu.Lock()
+			hooked = append(hooked, append([]string(nil), o.Info()...))
+			mu.Unlock()
+		}
+	})
+	Retry(3, time.Millisecond, func() error {
+		panic("TestRetry")
+	})
+	restore()
+	mu.Lock()
+	if len(hooked) != 1 || len(hooked[0]) != 2 || hooked[0][1] != "attempts: 3" {
+		t.Errorf(`Retry(3, panicFunc) passed to OnOutcome Outcomes with info %q, want only the last one, with %q`, hooked, "attempts: 3")
+	}
+	mu.Unlock()
+
+	calls = 0
+	out = Retry(0, time.Millisecond, func() error {
+		calls++
+		return fmt.Errorf("failed")
+	})
+	if oe := out.Err(); oe == nil || calls != 1 {
+		t.Errorf(`Retry(0, errFunc).Err() = %v after %d calls, want %q after %d calls`, oe, calls, "failed", 1)
+	}
+
+	start := time.Now()
+	RetryExp(4, 5*time.Millisecond, 2, func() error {
+		return fmt.Errorf("failed")
+	})
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf(`RetryExp(4, 5ms, 2, errFunc) took %v, want at least %v`, elapsed, 35*time.Millisecond)
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, Multiplier: 2, MaxDelay: 50 * time.Millisecond}
+	for _, test := range []struct {
+		attempt int
+		exp     time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 40 * time.Millisecond},
+		{4, 50 * time.Millisecond},
+		{10, 50 * time.Millisecond},
+	} {
+		if d := p.wait(test.attempt); d != test.exp {
+			t.Errorf(`RetryPolicy.wait(%d) = %s, want %s`, test.attempt, d, test.exp)
+		}
+	}
+
+	p.Jitter = 0.2
+	var lower, higher bool
+	for i := 0; i < 1000; i++ {
+		d := p.wait(2)
+		if d < 16*time.Millisecond || d > 24*time.Millisecond {
+			t.Fatalf(`RetryPolicy{Jitter: 0.2}.wait(2) = %s, want between %s and %s`, d, 16*time.Millisecond, 24*time.Millisecond)
+		}
+		lower, higher = lower || d < 20*time.Millisecond, higher || d > 20*time.Millisecond
+	}
+	if !lower || !higher {
+		t.Errorf(`RetryPolicy{Jitter: 0.2}.wait(2) is not randomized in both directions`)
+	}
+}
+
+func TestTryRetry(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	out := TryRetry(ctx, RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond}, func() error {
+		calls++
+		if calls < 3 {
+			panic("transient")
+		}
+		return nil
+	})
+	if ol, oe := out.Level(), out.Err(); ol != OK || oe != nil || calls != 3 || len(out.Info()) != 0 {
+		t.Errorf(`TryRetry(5, flaky) = (%q, %v, %q) after %d calls, want (%q, %v, none) after %d calls`, LevelName(ol), oe, out.Info(), calls, LevelName(OK), nil, 3)
+	}
+
+	calls = 0
+	failing := func() error {
+		calls++
+		return fmt.Errorf("failed")
+	}
+	out = TryRetry(ctx, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, failing)
+	if oi := out.Info(); calls != 3 || len(oi) != 2 || oi[0] != "attempts: 3" || !strings.HasPrefix(oi[1], "elapsed: ") {
+		t.Errorf(`TryRetry(3, errFunc).Info() = %q after %d calls, want attempts and elapsed after %d calls`, oi, calls, 3)
+	}
+
+	var mu sync.Mutex
+	var hooked [][]string
+	restore := setOnOutcome(func(o *Outcome) {
+		if o.PanicValue() == "TestTryRetry" {
+			mu.Lock()
+			hooked = append(hooked, append([]string(nil), o.Info()...))
+			mu.Unlock()
+		}
+	})
+	TryRetry(ctx, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, func() error {
+		panic("TestTryRetry")
+	})
+	restore()
+	mu.Lock()
+	if len(hooked) != 1 || len(hooked[0]) < 2 || hooked[0][len(hooked[0])-2] != "attempts: 3" {
+		t.Errorf(`TryRetry(3, panicFunc) passed to OnOutcome Outcomes with info %q, want only the last one, with attempts and elapsed`, hooked)
+	}
+	mu.Unlock()
+
+	// timings are only checked loosely, since the waits may take longer than
+	// requested on a loaded machine
+	calls = 0
+	out = TryRetry(ctx, RetryPolicy{MaxAttempts: 100, BaseDelay: 10 * time.Millisecond, MaxElapsed: 35 * time.Millisecond}, failing)
+	if oi := out.Info(); calls < 1 || calls > 4 || len(oi) != 2 || oi[0] != fmt.Sprintf("attempts: %d", calls) || !strings.HasPrefix(oi[1], "elapsed: ") {
+		t.Errorf(`TryRetry(MaxElapsed: 35ms, errFunc).Info() = %q after %d calls, want attempts and elapsed after at most %d calls`, oi, calls, 4)
+	}
+
+	calls = 0
+	cctx, cancel := context.WithTimeout(ctx, 15*time.Millisecond)
+	defer cancel()
+	out = TryRetry(cctx, RetryPolicy{MaxAttempts: 100, BaseDelay: 10 * time.Millisecond}, failing)
+	if oi := out.Info(); calls < 1 || len(oi) != 3 || oi[0] != fmt.Sprintf("attempts: %d", calls) || oi[2] != "TryRetry: context deadline exceeded" {
+		t.Errorf(`TryRetry(expiringCtx, errFunc).Info() = %q after %d calls, want the attempts and the context error`, oi, calls)
+	}
+	calls = 0
+	out = TryRetry(cctx, RetryPolicy{}, failing)
+	if oc := out.Code(); oc != ERR_TRY_CONTEXT || calls != 0 {
+		t.Errorf(`TryRetry(doneCtx, errFunc).Code() = 0x%04x after %d calls, want 0x%04x without calling`, oc, calls, ERR_TRY_CONTEXT)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"sync"
+)
+
+// SyncOutcome guards an Outcome with a mutex, for sharing it among goroutines,
+// e.g. to let concurrent workers add info to the same Outcome.
+type SyncOutcome struct {
+	mu sync.Mutex
+	o  *Outcome
+}
+
+// NewSyncOutcome returns a SyncOutcome guarding o, which must no longer be used
+// directly.
+func NewSyncOutcome(o *Outcome) *SyncOutcome {
+	return &SyncOutcome{o: o}
+}
+
+// Do calls f with the guarded Outcome, holding the lock, e.g. to apply several
+// changes atomically, or to use methods not provided by SyncOutcome.
+// f must not retain the Outcome, nor call the methods of the SyncOutcome.
+func (so *SyncOutcome) Do(f func(*Outcome)) *SyncOutcome {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	f(so.o)
+	return so
+}
+
+// Outcome returns a copy of the guarded Outcome (see Clone), which can be used
+// freely, without affecting the guarded one.
+func (so *SyncOutcome) Outcome() *Outcome {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	return so.o.Clone()
+}
+
+// AddInfo adds (more) error info to the guarded Outcome.
+func (so *SyncOutcome) AddInfo(s ...string) *SyncOutcome {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	so.o.addInfo(2, s...)
+	return so
+}
+
+// WithField adds a key/value pair to the structured context of the guarded Outcome.
+func (so *SyncOutcome) WithField(key string, value interface{}) *SyncOutcome {
+	return so.Do(func(o *Outcome) {
+		o.WithField(key, value)
+	})
+}
+
+// SetLevel sets the level of the guarded Outcome.
+func (so *SyncOutcome) SetLevel(l int8) *SyncOutcome {
+	return so.Do(func(o *Outcome) {
+		o.SetLevel(l)
+	})
+}
+
+// SetCode sets the error code of the guarded Outcome.
+func (so *SyncOutcome) SetCode(c int) *SyncOutcome {
+	return so.Do(func(o *Outcome) {
+		o.SetCode(c)
+	})
+}
+
+// SetText sets the error text of the guarded Outcome.
+func (so *SyncOutcome) SetText(t string) *SyncOutcome {
+	return so.Do(func(o *Outcome) {
+		o.SetText(t)
+	})
+}
+
+// Level returns the level of the guarded Outcome.
+func (so *SyncOutcome) Level() int8 {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	return so.o.level
+}
+
+// Code returns the error code of the guarded Outcome.
+func (so *SyncOutcome) Code() int {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	return so.o.code
+}
+
+// Text returns the error text of the guarded Outcome.
+func (so *SyncOutcome) Text() string {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	return so.o.text
+}
+
+// Info returns a copy of the error info of the guarded Outcome.
+func (so *SyncOutcome) Info() []string {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	return append([]string(nil), so.o.info...)
+}
@@ -0,0 +1,58 @@
+// Copyright 2015 ALRUX Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package calmly
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestSyncOutcome is meant to be run with -race: the same calls on an unguarded
+// Outcome are reported as data races.
+func TestSyncOutcome(t *testing.T) {
+	so := NewSyncOutcome(Try(func() { panic("test") }, WithoutStack()))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			so.AddInfo(fmt.Sprintf("worker %d", i)).WithField(fmt.Sprint(i), i)
+			if i == 5 {
+				so.SetLevel(ERROR).SetCode(17).SetText("abc")
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, _, _, _ = so.Info(), so.Level(), so.Code(), so.Text()
+			so.Outcome().AddInfo("snapshot")
+		}()
+	}
+	wg.Wait()
+	out := so.Outcome()
+	if ol, oc, ot := out.Level(), out.Code(), out.Text(); ol != ERROR || oc != 17 || ot != "abc" {
+		t.Errorf(`SyncOutcome = (%q, %d, %q), want (%q, %d, %q)`, LevelName(ol), oc, ot, LevelName(ERROR), 17, "abc")
+	}
+	if info, fields := so.Info(), out.Fields(); len(info) != 10 || len(fields) != 10 {
+		t.Errorf(`SyncOutcome has %d info lines and %d fields, want %d of each`, len(info), len(fields), 10)
+	}
+	so.Do(func(o *Outcome) {
+		o.AddInfo("debug.stack")
+	})
+	if info := so.Info(); !strings.HasPrefix(info[10], "goroutine ") {
+		t.Errorf(`SyncOutcome.Do(AddInfo("debug.stack")) did not add a stack trace (got %q)`, info[10])
+	}
+}